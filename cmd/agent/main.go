@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"uptime-monitor/internal/agent"
+	"uptime-monitor/internal/config"
+)
+
+func main() {
+	// Cancelled on SIGINT/SIGTERM; running checks finish and results are pushed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.LoadAgent()
+	if err != nil {
+		log.Fatalf("FATAL: could not load config: %v", err)
+	}
+
+	log.Printf("INFO: Starting probe agent for %s", cfg.APIURL)
+	agent.New(cfg).Run(ctx)
+	log.Println("INFO: Shutdown complete")
+}
@@ -1,14 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"uptime-monitor/internal/api"
 	"uptime-monitor/internal/config"
 	"uptime-monitor/internal/database"
+	"uptime-monitor/internal/database/db"
 	"uptime-monitor/internal/monitoring"
+	"uptime-monitor/internal/notifications"
 )
 
 func main() {
+	// Cancelled on SIGINT/SIGTERM to start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// 1. Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -16,7 +28,7 @@ func main() {
 	}
 
 	// 2. Initialize database connection
-	dbPool, err := database.Connect(cfg.DatabaseURL)
+	dbPool, err := database.Connect(ctx, cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("FATAL: could not connect to database: %v", err)
 	}
@@ -25,13 +37,51 @@ func main() {
 
 	// 3. Start the monitoring worker in the background
 	log.Println("INFO: Initializing monitoring worker...")
-	monitor := monitoring.NewMonitor(dbPool)
-	go monitor.Start() // Starts the worker in a new goroutine
-
-	// 4. Start the API server (this is a blocking call)
-	server := api.NewServer(dbPool)
-	log.Printf("INFO: Starting API server on %s", cfg.ServerAddress)
-	if err := server.Start(cfg.ServerAddress); err != nil {
-		log.Fatalf("FATAL: could not start server: %v", err)
+	monitor := monitoring.NewMonitor(cfg, dbPool)
+	go monitor.Start(ctx) // Stops scheduling new checks when ctx is cancelled
+
+	// Delivers the notifications the monitor queues in the outbox
+	dispatcher := notifications.NewDispatcher(cfg, db.New(dbPool))
+	go dispatcher.Start(ctx)
+
+	// Notifies the next level of escalation policies for unacknowledged incidents
+	escalator := monitoring.NewEscalator(cfg, dbPool)
+	go escalator.Start(ctx)
+
+	// 4. Start the API server in the background
+	server := api.NewServer(cfg, dbPool)
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("INFO: Starting API server on %s", cfg.ServerAddress)
+		serverErr <- server.Start(cfg.ServerAddress)
+	}()
+
+	// 5. Wait for a shutdown signal or a server failure
+	select {
+	case <-ctx.Done():
+		log.Println("INFO: Shutdown signal received")
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("ERROR: API server stopped: %v", err)
+		}
+		stop() // Stop the monitor too
+	}
+
+	// 6. Drain the API server and in-flight checks before closing the pool
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("ERROR: API server shutdown: %v", err)
+	}
+	if err := monitor.Shutdown(shutdownCtx); err != nil {
+		log.Printf("ERROR: Monitoring worker shutdown: %v", err)
+	}
+	if err := dispatcher.Shutdown(shutdownCtx); err != nil {
+		log.Printf("ERROR: Notification dispatcher shutdown: %v", err)
+	}
+	if err := escalator.Shutdown(shutdownCtx); err != nil {
+		log.Printf("ERROR: Escalation worker shutdown: %v", err)
 	}
+	log.Println("INFO: Shutdown complete")
 }
@@ -0,0 +1,207 @@
+// Package agent implements the remote probe agent. It pulls the services
+// assigned to its location from the central API, checks them with the same
+// checkers as the monitor and pushes the results back.
+package agent
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+	"uptime-monitor/internal/config"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/monitoring"
+)
+
+const (
+	// batchSize matches the maximum number of results the API accepts per request.
+	batchSize = 1000
+
+	// maxBufferedReports bounds the results kept while the API is unreachable.
+	// The oldest results are dropped first.
+	maxBufferedReports = 10000
+)
+
+// Agent runs checks for the services assigned to it.
+type Agent struct {
+	client *Client
+	probe  *monitoring.Probe
+
+	refreshInterval time.Duration
+	pushInterval    time.Duration
+
+	sem    chan struct{} // Limits concurrent checks
+	checks sync.WaitGroup
+
+	mu       sync.Mutex
+	services map[int64]*scheduledService
+	pending  []monitoring.Report // Results not yet pushed
+}
+
+type scheduledService struct {
+	service db.GetServicesAndOwnersRow
+	next    time.Time
+	running bool
+}
+
+// New creates an agent from its configuration.
+func New(cfg *config.AgentConfig) *Agent {
+	concurrency := cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Agent{
+		client:          NewClient(cfg.APIURL, cfg.Token),
+		probe:           monitoring.NewProbe(),
+		refreshInterval: cfg.RefreshInterval,
+		pushInterval:    cfg.PushInterval,
+		services:        make(map[int64]*scheduledService),
+		sem:             make(chan struct{}, concurrency),
+	}
+}
+
+// Run checks services until ctx is cancelled, then waits for running checks
+// and pushes the remaining results.
+func (a *Agent) Run(ctx context.Context) {
+	a.refresh(ctx)
+
+	refreshTicker := time.NewTicker(a.refreshInterval)
+	defer refreshTicker.Stop()
+	pushTicker := time.NewTicker(a.pushInterval)
+	defer pushTicker.Stop()
+	scheduleTicker := time.NewTicker(time.Second)
+	defer scheduleTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			a.checks.Wait()
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			a.flush(flushCtx)
+			cancel()
+			return
+		case <-refreshTicker.C:
+			a.refresh(ctx)
+		case <-pushTicker.C:
+			a.flush(ctx)
+		case now := <-scheduleTicker.C:
+			a.startDue(ctx, now)
+		}
+	}
+}
+
+// refresh replaces the service list with the one assigned by the API. New
+// services are spread over their first interval.
+func (a *Agent) refresh(ctx context.Context) {
+	services, err := a.client.FetchServices(ctx)
+	if err != nil {
+		log.Printf("ERROR: Could not fetch services: %v", err)
+		return
+	}
+
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	seen := make(map[int64]bool, len(services))
+	for _, s := range services {
+		seen[s.ID] = true
+		if existing, ok := a.services[s.ID]; ok {
+			existing.service = s
+			continue
+		}
+		interval := time.Duration(s.CheckIntervalSeconds) * time.Second
+		a.services[s.ID] = &scheduledService{service: s, next: now.Add(randomDuration(interval))}
+	}
+	for id := range a.services {
+		if !seen[id] {
+			delete(a.services, id)
+		}
+	}
+	log.Printf("INFO: Checking %d services", len(a.services))
+}
+
+// startDue starts a check for every due service that isn't already running.
+func (a *Agent) startDue(ctx context.Context, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, s := range a.services {
+		if s.running || now.Before(s.next) {
+			continue
+		}
+		s.running = true
+		s.next = now.Add(time.Duration(s.service.CheckIntervalSeconds) * time.Second)
+
+		a.checks.Add(1)
+		go func(scheduled *scheduledService, service db.GetServicesAndOwnersRow) {
+			defer a.checks.Done()
+			a.sem <- struct{}{}
+			defer func() { <-a.sem }()
+
+			a.check(ctx, service)
+
+			a.mu.Lock()
+			scheduled.running = false
+			a.mu.Unlock()
+		}(s, s.service)
+	}
+}
+
+// randomDuration returns a random duration in [0, max).
+func randomDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// check runs a single check and buffers the result.
+func (a *Agent) check(ctx context.Context, s db.GetServicesAndOwnersRow) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	result, err := a.probe.Check(ctx, s)
+	if err != nil {
+		log.Printf("ERROR: Could not check service %d: %v", s.ID, err)
+		return
+	}
+	// Don't report checks cut short by shutdown as failures
+	if ctx.Err() != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = append(a.pending, monitoring.NewReport(s.ID, result))
+	if len(a.pending) > maxBufferedReports {
+		a.pending = a.pending[len(a.pending)-maxBufferedReports:]
+	}
+}
+
+// flush pushes buffered results in batches. Results that could not be pushed
+// are kept for the next flush.
+func (a *Agent) flush(ctx context.Context) {
+	a.mu.Lock()
+	reports := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+
+	for len(reports) > 0 {
+		n := len(reports)
+		if n > batchSize {
+			n = batchSize
+		}
+		if err := a.client.PushResults(ctx, reports[:n]); err != nil {
+			log.Printf("ERROR: Could not push %d results: %v", len(reports), err)
+			a.mu.Lock()
+			a.pending = append(reports, a.pending...)
+			a.mu.Unlock()
+			return
+		}
+		reports = reports[n:]
+	}
+}
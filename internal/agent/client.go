@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/monitoring"
+)
+
+// Client talks to the agent endpoints of the central API.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the API at baseURL, authenticated with the agent token.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// FetchServices returns the services assigned to the agent's location.
+func (c *Client) FetchServices(ctx context.Context) ([]db.GetServicesAndOwnersRow, error) {
+	var services []db.GetServicesAndOwnersRow
+	if err := c.do(ctx, http.MethodGet, "/agent/services", nil, &services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// PushResults sends check results to the API.
+func (c *Client) PushResults(ctx context.Context, reports []monitoring.Report) error {
+	body, err := json.Marshal(reports)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, "/agent/results", body, nil)
+}
+
+// do sends an authenticated request and decodes the JSON response into out, if not nil.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: unexpected status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
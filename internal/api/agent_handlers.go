@@ -0,0 +1,231 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/monitoring"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxAgentReports caps the number of results an agent can push in one request.
+const maxAgentReports = 1000
+
+type agentInput struct {
+	Name     string `json:"name" binding:"required,max=255"`
+	Location string `json:"location" binding:"required,max=100"`
+}
+
+// agentResponse is an agent as returned to its owner, without the token hash.
+type agentResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Location   string     `json:"location"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	Token      string     `json:"token,omitempty"` // Only returned when the agent is created
+}
+
+func newAgentResponse(agent db.Agent) agentResponse {
+	resp := agentResponse{
+		ID:        agent.ID,
+		Name:      agent.Name,
+		Location:  agent.Location,
+		CreatedAt: agent.CreatedAt.Time,
+	}
+	if agent.LastSeenAt.Valid {
+		resp.LastSeenAt = &agent.LastSeenAt.Time
+	}
+	return resp
+}
+
+// agentAuthMiddleware authenticates probe agents by the token in the
+// Authorization header and sets the agent in the context.
+func (s *Server) agentAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		headerParts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Agent token required"})
+			return
+		}
+
+		agent, err := s.q.GetAgentByTokenHash(c.Request.Context(), hashToken(headerParts[1]))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid agent token"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+
+		if err := s.q.TouchAgent(c.Request.Context(), agent.ID); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+
+		c.Set("agent", agent)
+		c.Next()
+	}
+}
+
+// createAgent registers a probe agent for the authenticated user. The token is
+// only returned in this response.
+func (s *Server) createAgent(c *gin.Context) {
+	var input agentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if input.Location == monitoring.LocationCentral {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid input: location %q is reserved", input.Location)})
+		return
+	}
+
+	token, err := newToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate agent token"})
+		return
+	}
+
+	agent, err := s.q.CreateAgent(c.Request.Context(), db.CreateAgentParams{
+		UserID:    c.GetInt64("userID"),
+		Name:      input.Name,
+		Location:  input.Location,
+		TokenHash: hashToken(token),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create agent"})
+		return
+	}
+
+	resp := newAgentResponse(agent)
+	resp.Token = token
+	c.JSON(http.StatusCreated, resp)
+}
+
+// getAgents lists the probe agents of the authenticated user.
+func (s *Server) getAgents(c *gin.Context) {
+	agents, err := s.q.GetAgentsForUser(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve agents"})
+		return
+	}
+
+	resp := make([]agentResponse, 0, len(agents))
+	for _, agent := range agents {
+		resp = append(resp, newAgentResponse(agent))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// deleteAgent deletes a probe agent, revoking its token.
+func (s *Server) deleteAgent(c *gin.Context) {
+	agentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+		return
+	}
+
+	rowsAffected, err := s.q.DeleteAgent(c.Request.Context(), db.DeleteAgentParams{
+		ID:     agentID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete agent"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found or you do not have permission to delete it"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Agent deleted successfully"})
+}
+
+// agentServices returns the services assigned to the agent's location.
+func (s *Server) agentServices(c *gin.Context, agent db.Agent) ([]db.GetServicesForAgentRow, error) {
+	return s.q.GetServicesForAgent(c.Request.Context(), db.GetServicesForAgentParams{
+		UserID:   agent.UserID,
+		Location: agent.Location,
+	})
+}
+
+// getAgentServices returns the services the authenticated agent should check.
+func (s *Server) getAgentServices(c *gin.Context) {
+	agent := c.MustGet("agent").(db.Agent)
+
+	services, err := s.agentServices(c, agent)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve services"})
+		return
+	}
+	if services == nil {
+		services = []db.GetServicesForAgentRow{}
+	}
+
+	c.JSON(http.StatusOK, services)
+}
+
+// ingestAgentResults records check results pushed by the authenticated agent,
+// tagged with the agent's location.
+func (s *Server) ingestAgentResults(c *gin.Context) {
+	agent := c.MustGet("agent").(db.Agent)
+
+	var reports []monitoring.Report
+	if err := c.ShouldBindJSON(&reports); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if len(reports) > maxAgentReports {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid input: at most %d results per request", maxAgentReports)})
+		return
+	}
+
+	services, err := s.agentServices(c, agent)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve services"})
+		return
+	}
+	assigned := make(map[int64]bool, len(services))
+	for _, service := range services {
+		assigned[service.ID] = true
+	}
+
+	// Checks during a maintenance window are recorded with the maintenance status.
+	maintenance := make(map[int64]bool)
+	recorded := 0
+	for _, report := range reports {
+		// Services may be reassigned between a pull and a push; skip those.
+		if !assigned[report.ServiceID] {
+			continue
+		}
+		inMaintenance, ok := maintenance[report.ServiceID]
+		if !ok {
+			inMaintenance, err = monitoring.InMaintenance(c.Request.Context(), s.q, report.ServiceID, time.Now())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve maintenance windows"})
+				return
+			}
+			maintenance[report.ServiceID] = inMaintenance
+		}
+
+		params := report.StatusCheckParams(agent.Location)
+		if inMaintenance {
+			params = report.MaintenanceCheckParams(agent.Location)
+		}
+		if _, err := s.q.CreateStatusCheck(c.Request.Context(), params); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record results"})
+			return
+		}
+		recorded++
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recorded": recorded, "skipped": len(reports) - recorded})
+}
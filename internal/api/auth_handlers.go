@@ -1,7 +1,6 @@
 package api
 
 import (
-	"context"
 	"net/http"
 	"os"
 	"strings"
@@ -41,7 +40,7 @@ func (s *Server) registerUser(c *gin.Context) {
 		PasswordHash: hashedPassword,
 	}
 
-	newUser, err := s.q.CreateUser(context.Background(), params)
+	newUser, err := s.q.CreateUser(c.Request.Context(), params)
 	if err != nil {
 		// Manejar error de email duplicado
 		if strings.Contains(err.Error(), "unique constraint") {
@@ -63,7 +62,7 @@ func (s *Server) loginUser(c *gin.Context) {
 		return
 	}
 
-	user, err := s.q.GetUserByEmail(context.Background(), input.Email)
+	user, err := s.q.GetUserByEmail(c.Request.Context(), input.Email)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
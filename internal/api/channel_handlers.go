@@ -0,0 +1,231 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/notifications"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type channelInput struct {
+	Type      string `json:"type" binding:"required,oneof=email slack discord teams webhook pagerduty opsgenie oncall"`
+	Name      string `json:"name" binding:"required,max=255"`
+	Target    string `json:"target" binding:"required"` // Address, webhook URL, paging routing/API key or schedule ID
+	ServiceID *int64 `json:"service_id"`                // Omit to apply to all services
+	Enabled   *bool  `json:"enabled"`
+
+	// Webhook channels only: HMAC signing key, generated if omitted
+	Secret string `json:"secret" binding:"omitempty,min=16"`
+}
+
+// isPagingChannel reports whether the channel type pages through PagerDuty or Opsgenie.
+func isPagingChannel(channelType string) bool {
+	return channelType == notifications.ChannelPagerDuty || channelType == notifications.ChannelOpsgenie
+}
+
+// redactChannel hides the webhook signing secret and paging keys before a
+// channel is sent to the client.
+func redactChannel(channel *db.NotificationChannel) {
+	if channel.Secret.Valid {
+		channel.Secret.String = "********"
+	}
+	if isPagingChannel(channel.Type) {
+		channel.Target = "********"
+	}
+}
+
+// validateChannelTarget checks that the target is an email address for email
+// channels, a routing or API key for paging channels, a schedule ID for
+// on-call channels and a webhook URL for the others.
+func validateChannelTarget(channelType, target string) error {
+	switch {
+	case channelType == notifications.ChannelEmail:
+		if _, err := mail.ParseAddress(target); err != nil {
+			return fmt.Errorf("target must be an email address")
+		}
+		return nil
+	case isPagingChannel(channelType):
+		if strings.ContainsAny(target, " /") {
+			return fmt.Errorf("target must be the %s integration key", channelType)
+		}
+		return nil
+	case channelType == notifications.ChannelOnCall:
+		if _, err := strconv.ParseInt(target, 10, 64); err != nil {
+			return fmt.Errorf("target must be an on-call schedule ID")
+		}
+		return nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("target must be an http(s) webhook URL")
+	}
+	return nil
+}
+
+// createNotificationChannel adds a notification channel for the authenticated user.
+func (s *Server) createNotificationChannel(c *gin.Context) {
+	var input channelInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if err := validateChannelTarget(input.Type, input.Target); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	params := db.CreateNotificationChannelParams{
+		UserID:  userID,
+		Type:    input.Type,
+		Name:    input.Name,
+		Target:  input.Target,
+		Enabled: true,
+	}
+	if input.Enabled != nil {
+		params.Enabled = *input.Enabled
+	}
+
+	if input.ServiceID != nil {
+		_, err := s.q.GetServiceForUser(c.Request.Context(), db.GetServiceForUserParams{
+			ID:     *input.ServiceID,
+			UserID: userID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve service"})
+			return
+		}
+		params.ServiceID = pgtype.Int8{Int64: *input.ServiceID, Valid: true}
+	}
+
+	if input.Type == notifications.ChannelOnCall {
+		scheduleID, _ := strconv.ParseInt(input.Target, 10, 64)
+		if _, ok := s.oncallScheduleID(c, &scheduleID); !ok {
+			return
+		}
+	}
+
+	if input.Type == notifications.ChannelWebhook {
+		if input.Secret == "" {
+			secret, err := newToken()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook secret"})
+				return
+			}
+			input.Secret = secret
+		}
+		params.Secret = optionalText(input.Secret)
+	}
+
+	channel, err := s.q.CreateNotificationChannel(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notification channel"})
+		return
+	}
+
+	// The webhook secret is only returned here
+	c.JSON(http.StatusCreated, channel)
+}
+
+// getNotificationChannels lists the notification channels of the authenticated user.
+func (s *Server) getNotificationChannels(c *gin.Context) {
+	channels, err := s.q.GetNotificationChannelsForUser(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notification channels"})
+		return
+	}
+	if channels == nil {
+		channels = []db.NotificationChannel{}
+	}
+	for i := range channels {
+		redactChannel(&channels[i])
+	}
+
+	c.JSON(http.StatusOK, channels)
+}
+
+// deleteNotificationChannel deletes a notification channel of the authenticated user.
+func (s *Server) deleteNotificationChannel(c *gin.Context) {
+	channelID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel ID"})
+		return
+	}
+
+	rowsAffected, err := s.q.DeleteNotificationChannel(c.Request.Context(), db.DeleteNotificationChannelParams{
+		ID:     channelID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notification channel"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification channel not found or you do not have permission to delete it"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification channel deleted successfully"})
+}
+
+// getChannelDeliveries returns the latest delivery attempts of a webhook or
+// paging channel.
+func (s *Server) getChannelDeliveries(c *gin.Context) {
+	channelID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel ID"})
+		return
+	}
+
+	channel, err := s.q.GetNotificationChannelForUser(c.Request.Context(), db.GetNotificationChannelForUserParams{
+		ID:     channelID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification channel not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notification channel"})
+		return
+	}
+
+	switch {
+	case channel.Type == notifications.ChannelWebhook:
+		deliveries, err := s.q.GetWebhookDeliveriesForChannel(c.Request.Context(), channel.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve deliveries"})
+			return
+		}
+		if deliveries == nil {
+			deliveries = []db.WebhookDelivery{}
+		}
+		c.JSON(http.StatusOK, deliveries)
+	case isPagingChannel(channel.Type):
+		deliveries, err := s.q.GetPagingDeliveriesForChannel(c.Request.Context(), channel.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve deliveries"})
+			return
+		}
+		if deliveries == nil {
+			deliveries = []db.PagingDelivery{}
+		}
+		c.JSON(http.StatusOK, deliveries)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Deliveries are only recorded for webhook and paging channels"})
+	}
+}
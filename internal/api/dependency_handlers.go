@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type dependenciesInput struct {
+	DependsOn []int64 `json:"depends_on" binding:"max=50,dive,required"`
+}
+
+// invalidDependencyError is returned by setDependencies when the requested
+// dependencies are not allowed.
+type invalidDependencyError struct {
+	msg string
+}
+
+func (e *invalidDependencyError) Error() string { return e.msg }
+
+// findCycle returns the path from parent back to serviceID if serviceID
+// depending on parent would create a cycle in the dependency graph given as
+// the parents of each service, or nil otherwise.
+func findCycle(parents map[int64][]int64, serviceID, parent int64) []int64 {
+	visited := make(map[int64]bool)
+	var visit func(id int64) []int64
+	visit = func(id int64) []int64 {
+		if id == serviceID {
+			return []int64{id}
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+		for _, next := range parents[id] {
+			if path := visit(next); path != nil {
+				return append([]int64{id}, path...)
+			}
+		}
+		return nil
+	}
+	return visit(parent)
+}
+
+// dependencyGraph returns the parents of each service from the dependency
+// edges, leaving out the edges of serviceID whose dependencies are replaced.
+func dependencyGraph(edges []db.ServiceDependency, serviceID int64) map[int64][]int64 {
+	parents := make(map[int64][]int64)
+	for _, edge := range edges {
+		if edge.ServiceID != serviceID {
+			parents[edge.ServiceID] = append(parents[edge.ServiceID], edge.DependsOnID)
+		}
+	}
+	return parents
+}
+
+// setDependencies replaces the services that serviceID depends on, using q
+// which must be bound to a transaction. Dependencies must belong to the user
+// and must not create a cycle.
+func setDependencies(ctx context.Context, q *db.Queries, userID, serviceID int64, dependsOn []int64) ([]int64, error) {
+	if err := q.LockServiceDependencies(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	edges, err := q.GetDependencyEdgesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	parents := dependencyGraph(edges, serviceID)
+
+	if err := q.DeleteServiceDependencies(ctx, serviceID); err != nil {
+		return nil, err
+	}
+	added := []int64{}
+	seen := make(map[int64]bool)
+	for _, parent := range dependsOn {
+		if seen[parent] {
+			continue
+		}
+		seen[parent] = true
+		if parent == serviceID {
+			return nil, &invalidDependencyError{"a service cannot depend on itself"}
+		}
+
+		_, err := q.GetServiceForUser(ctx, db.GetServiceForUserParams{ID: parent, UserID: userID})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, &invalidDependencyError{fmt.Sprintf("service %d not found", parent)}
+			}
+			return nil, err
+		}
+
+		if path := findCycle(parents, serviceID, parent); path != nil {
+			ids := make([]string, 0, len(path)+1)
+			ids = append(ids, strconv.FormatInt(serviceID, 10))
+			for _, id := range path {
+				ids = append(ids, strconv.FormatInt(id, 10))
+			}
+			return nil, &invalidDependencyError{fmt.Sprintf("dependency on service %d would create a cycle: %s", parent, strings.Join(ids, " -> "))}
+		}
+		parents[serviceID] = append(parents[serviceID], parent)
+
+		err = q.AddServiceDependency(ctx, db.AddServiceDependencyParams{ServiceID: serviceID, DependsOnID: parent})
+		if err != nil {
+			return nil, err
+		}
+		added = append(added, parent)
+	}
+	return added, nil
+}
+
+// serviceParam parses the service ID from the URL and checks that it belongs
+// to the authenticated user. It writes the error response and returns false
+// otherwise.
+func (s *Server) serviceParam(c *gin.Context) (int64, bool) {
+	serviceID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return 0, false
+	}
+
+	_, err = s.q.GetServiceForUser(c.Request.Context(), db.GetServiceForUserParams{
+		ID:     serviceID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return 0, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve service"})
+		return 0, false
+	}
+	return serviceID, true
+}
+
+// getServiceDependencies lists the IDs of the services a service depends on.
+func (s *Server) getServiceDependencies(c *gin.Context) {
+	serviceID, ok := s.serviceParam(c)
+	if !ok {
+		return
+	}
+
+	dependsOn, err := s.q.GetServiceDependencies(c.Request.Context(), serviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dependencies"})
+		return
+	}
+	if dependsOn == nil {
+		dependsOn = []int64{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"depends_on": dependsOn})
+}
+
+// updateServiceDependencies replaces the services a service depends on.
+// Changes that would create a dependency cycle are rejected.
+func (s *Server) updateServiceDependencies(c *gin.Context) {
+	serviceID, ok := s.serviceParam(c)
+	if !ok {
+		return
+	}
+
+	var input dependenciesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update dependencies"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	dependsOn, err := setDependencies(ctx, s.q.WithTx(tx), c.GetInt64("userID"), serviceID, input.DependsOn)
+	if err != nil {
+		var invalid *invalidDependencyError
+		if errors.As(err, &invalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + invalid.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update dependencies"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update dependencies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"depends_on": dependsOn})
+}
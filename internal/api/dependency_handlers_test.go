@@ -0,0 +1,49 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"uptime-monitor/internal/database/db"
+)
+
+func TestFindCycle(t *testing.T) {
+	edge := func(serviceID, dependsOnID int64) db.ServiceDependency {
+		return db.ServiceDependency{ServiceID: serviceID, DependsOnID: dependsOnID}
+	}
+	tests := []struct {
+		name      string
+		edges     []db.ServiceDependency // Existing dependencies
+		serviceID int64
+		dependsOn []int64 // Added in order
+		want      []int64 // Path of the first cycle, nil if there is none
+	}{
+		{"self edge", nil, 1, []int64{1}, []int64{1}},
+		{"no edges", nil, 1, []int64{2}, nil},
+		{"two-node cycle", []db.ServiceDependency{edge(2, 1)}, 1, []int64{2}, []int64{2, 1}},
+		{"three-node cycle", []db.ServiceDependency{edge(2, 3), edge(3, 1)}, 1, []int64{2}, []int64{2, 3, 1}},
+		{"chain", []db.ServiceDependency{edge(2, 3), edge(3, 4)}, 1, []int64{2}, nil},
+		{"diamond", []db.ServiceDependency{edge(2, 4), edge(3, 4)}, 1, []int64{2, 3}, nil},
+		{"diamond from the top", []db.ServiceDependency{edge(1, 2), edge(1, 3), edge(2, 4), edge(3, 4)}, 5, []int64{1, 4}, nil},
+		{"closing a diamond", []db.ServiceDependency{edge(1, 2), edge(1, 3), edge(2, 4), edge(3, 4)}, 4, []int64{1}, []int64{1, 2, 4}},
+		{"cycle through an added edge", []db.ServiceDependency{edge(3, 1)}, 1, []int64{2, 3}, []int64{3, 1}},
+
+		// The service's existing dependencies are replaced, not added to
+		{"replacing an edge", []db.ServiceDependency{edge(1, 2), edge(3, 2)}, 1, []int64{3}, nil},
+		{"replacing an edge with itself", []db.ServiceDependency{edge(1, 2), edge(2, 3)}, 1, []int64{2}, nil},
+		{"replacing the edge of a cycle", []db.ServiceDependency{edge(1, 2), edge(2, 3)}, 3, []int64{4}, nil},
+		{"replacing into a cycle", []db.ServiceDependency{edge(1, 2), edge(2, 3)}, 3, []int64{1}, []int64{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		parents := dependencyGraph(tt.edges, tt.serviceID)
+		var got []int64
+		for _, parent := range tt.dependsOn {
+			if got = findCycle(parents, tt.serviceID, parent); got != nil {
+				break
+			}
+			parents[tt.serviceID] = append(parents[tt.serviceID], parent)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: findCycle = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/notifications"
+
+	"github.com/gin-gonic/gin"
+)
+
+type emailTemplateInput struct {
+	Content string `json:"content" binding:"required,max=65536"`
+}
+
+// getEmailTemplates lists the email templates the authenticated user has
+// overridden. The others are the built-in ones.
+func (s *Server) getEmailTemplates(c *gin.Context) {
+	templates, err := s.q.GetEmailTemplates(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve email templates"})
+		return
+	}
+	if templates == nil {
+		templates = []db.EmailTemplate{}
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// updateEmailTemplate overrides one of the email templates for the alerts of
+// the authenticated user. The template must render a sample alert.
+func (s *Server) updateEmailTemplate(c *gin.Context) {
+	name := c.Param("name")
+	if !slices.Contains(notifications.EmailTemplateNames, name) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+
+	var input emailTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if err := notifications.ValidateEmailTemplate(name, input.Content); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	template, err := s.q.UpsertEmailTemplate(c.Request.Context(), db.UpsertEmailTemplateParams{
+		UserID:  c.GetInt64("userID"),
+		Name:    name,
+		Content: input.Content,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save email template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// deleteEmailTemplate restores the built-in email template for the
+// authenticated user.
+func (s *Server) deleteEmailTemplate(c *gin.Context) {
+	rowsAffected, err := s.q.DeleteEmailTemplate(c.Request.Context(), db.DeleteEmailTemplateParams{
+		UserID: c.GetInt64("userID"),
+		Name:   c.Param("name"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete email template"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email template restored to the default"})
+}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/monitoring"
+	"uptime-monitor/internal/notifications"
+	"uptime-monitor/internal/web"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type escalationLevelInput struct {
+	DelayMinutes int      `json:"delay_minutes" binding:"min=0,max=10080"`
+	ChannelIDs   []int64  `json:"channel_ids" binding:"max=20,dive,required"`
+	Emails       []string `json:"emails" binding:"max=20,dive,required,email"`
+}
+
+type escalationPolicyInput struct {
+	Name   string                 `json:"name" binding:"required,max=255"`
+	Levels []escalationLevelInput `json:"levels" binding:"required,min=1,max=10,dive"`
+}
+
+// escalationPolicyDetail is an escalation policy with its levels in order.
+type escalationPolicyDetail struct {
+	db.EscalationPolicy
+	Levels []db.EscalationLevel `json:"levels"`
+}
+
+// validate checks that every level has a target and that the levels are in
+// the order they are notified.
+func (in *escalationPolicyInput) validate() error {
+	for i, level := range in.Levels {
+		if len(level.ChannelIDs) == 0 && len(level.Emails) == 0 {
+			return fmt.Errorf("level %d has no channel or email to notify", i+1)
+		}
+		if i > 0 && level.DelayMinutes < in.Levels[i-1].DelayMinutes {
+			return fmt.Errorf("level %d is notified before level %d", i+1, i)
+		}
+	}
+	return nil
+}
+
+// createEscalationPolicy creates an escalation policy for the authenticated
+// user. Its levels are notified in order, each delay_minutes after an
+// incident opens unless it was acknowledged or resolved by then.
+func (s *Server) createEscalationPolicy(c *gin.Context) {
+	var input escalationPolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if err := input.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+	for _, level := range input.Levels {
+		for _, channelID := range level.ChannelIDs {
+			_, err := s.q.GetNotificationChannelForUser(ctx, db.GetNotificationChannelForUserParams{
+				ID:     channelID,
+				UserID: userID,
+			})
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Notification channel %d not found", channelID)})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notification channel"})
+				return
+			}
+		}
+	}
+
+	// The policy and its levels are created together
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create escalation policy"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	q := s.q.WithTx(tx)
+
+	policy, err := q.CreateEscalationPolicy(ctx, db.CreateEscalationPolicyParams{
+		UserID: userID,
+		Name:   input.Name,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create escalation policy"})
+		return
+	}
+	detail := escalationPolicyDetail{EscalationPolicy: policy}
+	for i, level := range input.Levels {
+		created, err := q.CreateEscalationLevel(ctx, db.CreateEscalationLevelParams{
+			PolicyID:     policy.ID,
+			Position:     int32(i),
+			DelayMinutes: int32(level.DelayMinutes),
+			ChannelIds:   append([]int64{}, level.ChannelIDs...),
+			Emails:       append([]string{}, level.Emails...),
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create escalation policy"})
+			return
+		}
+		detail.Levels = append(detail.Levels, created)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create escalation policy"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, detail)
+}
+
+// getEscalationPolicies lists the escalation policies of the authenticated
+// user with their levels.
+func (s *Server) getEscalationPolicies(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+
+	policies, err := s.q.GetEscalationPoliciesForUser(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve escalation policies"})
+		return
+	}
+	levels, err := s.q.GetEscalationLevelsForUser(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve escalation policies"})
+		return
+	}
+
+	byPolicy := make(map[int64][]db.EscalationLevel)
+	for _, level := range levels {
+		byPolicy[level.PolicyID] = append(byPolicy[level.PolicyID], level)
+	}
+	details := make([]escalationPolicyDetail, 0, len(policies))
+	for _, policy := range policies {
+		details = append(details, escalationPolicyDetail{EscalationPolicy: policy, Levels: byPolicy[policy.ID]})
+	}
+
+	c.JSON(http.StatusOK, details)
+}
+
+// deleteEscalationPolicy deletes an escalation policy of the authenticated
+// user. Its services go back to notifying their channels directly.
+func (s *Server) deleteEscalationPolicy(c *gin.Context) {
+	policyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid escalation policy ID"})
+		return
+	}
+
+	rowsAffected, err := s.q.DeleteEscalationPolicy(c.Request.Context(), db.DeleteEscalationPolicyParams{
+		ID:     policyID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete escalation policy"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Escalation policy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Escalation policy deleted successfully"})
+}
+
+// updateServiceEscalationPolicy sets or, with a null escalation_policy_id,
+// clears the escalation policy of a service. Incidents already escalating
+// continue with the remaining levels of the new policy.
+func (s *Server) updateServiceEscalationPolicy(c *gin.Context) {
+	serviceID, ok := s.serviceParam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		EscalationPolicyID *int64 `json:"escalation_policy_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+	policyID, ok := s.escalationPolicyParam(c, input.EscalationPolicyID)
+	if !ok {
+		return
+	}
+
+	_, err := s.q.SetServiceEscalationPolicy(ctx, db.SetServiceEscalationPolicyParams{
+		EscalationPolicyID: policyID,
+		ID:                 serviceID,
+		UserID:             userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update escalation policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"escalation_policy_id": input.EscalationPolicyID})
+}
+
+// escalationPolicyParam checks that the escalation policy, if any, belongs to
+// the authenticated user. It writes the error response and returns false
+// otherwise.
+func (s *Server) escalationPolicyParam(c *gin.Context, policyID *int64) (pgtype.Int8, bool) {
+	if policyID == nil {
+		return pgtype.Int8{}, true
+	}
+
+	_, err := s.q.GetEscalationPolicyForUser(c.Request.Context(), db.GetEscalationPolicyForUserParams{
+		ID:     *policyID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Escalation policy not found"})
+			return pgtype.Int8{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve escalation policy"})
+		return pgtype.Int8{}, false
+	}
+	return pgtype.Int8{Int64: *policyID, Valid: true}, true
+}
+
+// ackLinkParams checks the signature and expiry of an acknowledgement link
+// from an alert email. The link is only valid for the recipient it was sent
+// to and until it expires. It renders the error page and returns false
+// otherwise.
+func (s *Server) ackLinkParams(c *gin.Context) (incidentID int64, email string, ok bool) {
+	incidentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		web.RenderLinkPage(c, http.StatusBadRequest, web.LinkPage{Title: "Invalid link", Message: "Invalid incident ID."})
+		return 0, "", false
+	}
+	expiresUnix, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		web.RenderLinkPage(c, http.StatusBadRequest, web.LinkPage{Title: "Invalid link", Message: "Invalid acknowledgement link."})
+		return 0, "", false
+	}
+
+	email = c.Query("email")
+	expires := time.Unix(expiresUnix, 0)
+	if !notifications.VerifyAckLink(s.cfg.JWTSecret, incidentID, email, expires, c.Query("sig")) {
+		web.RenderLinkPage(c, http.StatusForbidden, web.LinkPage{Title: "Invalid link", Message: "Invalid or expired acknowledgement link."})
+		return 0, "", false
+	}
+	return incidentID, email, true
+}
+
+// showAckLink asks the recipient of an acknowledgement link to confirm,
+// without acknowledging anything yet.
+func (s *Server) showAckLink(c *gin.Context) {
+	incidentID, _, ok := s.ackLinkParams(c)
+	if !ok {
+		return
+	}
+
+	web.RenderLinkPage(c, http.StatusOK, web.LinkPage{
+		Title:   "Acknowledge incident",
+		Message: fmt.Sprintf("Acknowledge incident #%d and stop its escalation?", incidentID),
+		Button:  "Acknowledge",
+	})
+}
+
+// acknowledgeIncidentByLink acknowledges an incident from the form shown by
+// the signed link in an alert email, without logging in.
+func (s *Server) acknowledgeIncidentByLink(c *gin.Context) {
+	incidentID, email, ok := s.ackLinkParams(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	incident, err := s.q.AcknowledgeIncidentByLink(ctx, incidentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			web.RenderLinkPage(c, http.StatusConflict, web.LinkPage{Title: "Acknowledge incident", Message: "Incident is already acknowledged or resolved."})
+			return
+		}
+		web.RenderLinkPage(c, http.StatusInternalServerError, web.LinkPage{Title: "Acknowledge incident", Message: "Failed to acknowledge incident."})
+		return
+	}
+
+	_, err = s.q.CreateIncidentEvent(ctx, db.CreateIncidentEventParams{
+		IncidentID: incident.ID,
+		Kind:       monitoring.EventAcknowledged,
+		Message:    optionalText("Acknowledged via email link by " + email),
+	})
+	if err != nil {
+		web.RenderLinkPage(c, http.StatusInternalServerError, web.LinkPage{Title: "Acknowledge incident", Message: "Failed to update incident timeline."})
+		return
+	}
+
+	web.RenderLinkPage(c, http.StatusOK, web.LinkPage{Title: "Acknowledge incident", Message: fmt.Sprintf("Incident #%d acknowledged.", incident.ID)})
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/monitoring"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxHeartbeatPayload caps the log output stored with a heartbeat.
+const maxHeartbeatPayload = 10 * 1024
+
+// receiveHeartbeat records a ping from a job on its service. The optional
+// kind is "start", "success" or "fail"; a plain ping counts as success unless
+// exit_code is non-zero. The request body, if any, is stored as the run's log
+// output. The monitor evaluates the service at its next check.
+func (s *Server) receiveHeartbeat(c *gin.Context) {
+	token := c.Param("token")
+	kind := c.Param("kind")
+	if kind != "" && kind != "start" && kind != monitoring.HeartbeatSuccess && kind != monitoring.HeartbeatFail {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown heartbeat type"})
+		return
+	}
+
+	service, err := s.q.GetServiceByHeartbeatToken(c.Request.Context(), pgtype.Text{String: token, Valid: true})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Heartbeat not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if kind == "start" {
+		if err := s.q.StartHeartbeat(c.Request.Context(), service.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record heartbeat"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "OK"})
+		return
+	}
+
+	params := db.RecordHeartbeatParams{
+		ID:                  service.ID,
+		LastHeartbeatStatus: pgtype.Text{String: monitoring.HeartbeatSuccess, Valid: true},
+	}
+
+	if exitCodeParam := c.Query("exit_code"); exitCodeParam != "" {
+		exitCode, err := strconv.Atoi(exitCodeParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exit_code"})
+			return
+		}
+		params.LastHeartbeatExitCode = pgtype.Int4{Int32: int32(exitCode), Valid: true}
+		if exitCode != 0 && kind == "" {
+			kind = monitoring.HeartbeatFail
+		}
+	}
+	if kind == monitoring.HeartbeatFail {
+		params.LastHeartbeatStatus.String = monitoring.HeartbeatFail
+	}
+
+	// Run duration is known when the job also pinged /start
+	if service.HeartbeatStartedAt.Valid {
+		duration := time.Since(service.HeartbeatStartedAt.Time)
+		params.LastHeartbeatDurationMs = pgtype.Int4{Int32: int32(duration.Milliseconds()), Valid: true}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxHeartbeatPayload))
+	if err == nil && len(body) > 0 {
+		params.LastHeartbeatPayload = pgtype.Text{String: string(body), Valid: true}
+	}
+
+	if err := s.q.RecordHeartbeat(c.Request.Context(), params); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record heartbeat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "OK"})
+}
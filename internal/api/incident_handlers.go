@@ -0,0 +1,350 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/monitoring"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type createIncidentInput struct {
+	ServiceID int64  `json:"service_id" binding:"required"`
+	Title     string `json:"title" binding:"required,max=255"`
+	Message   string `json:"message"`
+}
+
+type incidentNoteInput struct {
+	Message string `json:"message" binding:"required"`
+}
+
+type incidentUpdateInput struct {
+	Status  string `json:"status" binding:"required,oneof=investigating identified monitoring resolved"`
+	Message string `json:"message" binding:"required,max=5000"`
+}
+
+// incidentDetail is an incident with its timeline and the updates posted
+// for status page visitors.
+type incidentDetail struct {
+	db.GetIncidentForUserRow
+	Events  []db.IncidentEvent  `json:"events"`
+	Updates []db.IncidentUpdate `json:"updates"`
+}
+
+// incidentParam parses the incident ID from the URL and checks that it
+// belongs to the authenticated user. It writes the error response and returns
+// false otherwise.
+func (s *Server) incidentParam(c *gin.Context) (db.GetIncidentForUserRow, bool) {
+	incidentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid incident ID"})
+		return db.GetIncidentForUserRow{}, false
+	}
+
+	incident, err := s.q.GetIncidentForUser(c.Request.Context(), db.GetIncidentForUserParams{
+		ID:     incidentID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+			return incident, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve incident"})
+		return incident, false
+	}
+	return incident, true
+}
+
+// addIncidentEvent appends an entry to the incident timeline.
+func (s *Server) addIncidentEvent(c *gin.Context, incidentID int64, kind, message string) (db.IncidentEvent, error) {
+	return s.q.CreateIncidentEvent(c.Request.Context(), db.CreateIncidentEventParams{
+		IncidentID: incidentID,
+		Kind:       kind,
+		Message:    optionalText(message),
+		UserID:     pgtype.Int8{Int64: c.GetInt64("userID"), Valid: true},
+	})
+}
+
+// getIncidents lists the latest incidents of the authenticated user's
+// services, optionally filtered by ?status=open|acknowledged|resolved.
+func (s *Server) getIncidents(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", monitoring.IncidentOpen, monitoring.IncidentAcknowledged, monitoring.IncidentResolved:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status filter"})
+		return
+	}
+
+	incidents, err := s.q.GetIncidentsForUser(c.Request.Context(), db.GetIncidentsForUserParams{
+		UserID: c.GetInt64("userID"),
+		Status: optionalText(status),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve incidents"})
+		return
+	}
+	if incidents == nil {
+		incidents = []db.GetIncidentsForUserRow{}
+	}
+
+	c.JSON(http.StatusOK, incidents)
+}
+
+// createIncident opens an incident manually. If the service goes down while
+// it is open, the outage joins it and escalates it. Manual incidents are not
+// resolved automatically when the service recovers.
+func (s *Server) createIncident(c *gin.Context) {
+	var input createIncidentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+
+	_, err := s.q.GetServiceForUser(c.Request.Context(), db.GetServiceForUserParams{
+		ID:     input.ServiceID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve service"})
+		return
+	}
+
+	incident, err := s.q.OpenIncident(c.Request.Context(), db.OpenIncidentParams{
+		ServiceID:  input.ServiceID,
+		Title:      input.Title,
+		FirstError: optionalText(input.Message),
+		OpenedBy:   pgtype.Int8{Int64: userID, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The service already has an unresolved incident"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create incident"})
+		return
+	}
+
+	if _, err := s.addIncidentEvent(c, incident.ID, monitoring.EventOpened, input.Message); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update incident timeline"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, incident)
+}
+
+// getIncident returns an incident with its timeline.
+func (s *Server) getIncident(c *gin.Context) {
+	incident, ok := s.incidentParam(c)
+	if !ok {
+		return
+	}
+
+	events, err := s.q.GetIncidentEvents(c.Request.Context(), incident.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve incident timeline"})
+		return
+	}
+	if events == nil {
+		events = []db.IncidentEvent{}
+	}
+
+	updates, err := s.q.GetIncidentUpdates(c.Request.Context(), incident.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve incident updates"})
+		return
+	}
+	if updates == nil {
+		updates = []db.IncidentUpdate{}
+	}
+
+	c.JSON(http.StatusOK, incidentDetail{GetIncidentForUserRow: incident, Events: events, Updates: updates})
+}
+
+// acknowledgeIncident records that the authenticated user is handling an open incident.
+func (s *Server) acknowledgeIncident(c *gin.Context) {
+	current, ok := s.incidentParam(c)
+	if !ok {
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	incident, err := s.q.AcknowledgeIncident(c.Request.Context(), db.AcknowledgeIncidentParams{
+		UserID: userID,
+		ID:     current.ID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only open incidents can be acknowledged"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge incident"})
+		return
+	}
+
+	if _, err := s.addIncidentEvent(c, incident.ID, monitoring.EventAcknowledged, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update incident timeline"})
+		return
+	}
+
+	c.JSON(http.StatusOK, incident)
+}
+
+// resolveIncident resolves an incident manually.
+func (s *Server) resolveIncident(c *gin.Context) {
+	current, ok := s.incidentParam(c)
+	if !ok {
+		return
+	}
+
+	// The resolution message is optional
+	var input struct {
+		Message string `json:"message"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+			return
+		}
+	}
+
+	incident, err := s.q.ResolveIncident(c.Request.Context(), db.ResolveIncidentParams{
+		UserID: c.GetInt64("userID"),
+		ID:     current.ID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Incident is already resolved"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve incident"})
+		return
+	}
+
+	if _, err := s.addIncidentEvent(c, incident.ID, monitoring.EventResolved, input.Message); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update incident timeline"})
+		return
+	}
+
+	c.JSON(http.StatusOK, incident)
+}
+
+// addIncidentNote adds a note to the incident timeline.
+func (s *Server) addIncidentNote(c *gin.Context) {
+	incident, ok := s.incidentParam(c)
+	if !ok {
+		return
+	}
+
+	var input incidentNoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	event, err := s.addIncidentEvent(c, incident.ID, monitoring.EventNote, input.Message)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add note"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, event)
+}
+
+// postIncidentUpdate posts an update on an incident for status page visitors
+// and sends it to the subscribers of the pages showing its service. An
+// identified or monitoring update acknowledges an open incident and a
+// resolved update resolves it.
+func (s *Server) postIncidentUpdate(c *gin.Context) {
+	incident, ok := s.incidentParam(c)
+	if !ok {
+		return
+	}
+
+	var input incidentUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if incident.Status == monitoring.IncidentResolved && input.Status != monitoring.UpdateResolved {
+		c.JSON(http.StatusConflict, gin.H{"error": "Incident is already resolved"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := pgtype.Int8{Int64: c.GetInt64("userID"), Valid: true}
+
+	// The update, the status change and the notifications are recorded together
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post update"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	q := s.q.WithTx(tx)
+
+	var kind string
+	switch input.Status {
+	case monitoring.UpdateIdentified, monitoring.UpdateMonitoring:
+		if incident.Status == monitoring.IncidentOpen {
+			_, err = q.AcknowledgeIncident(ctx, db.AcknowledgeIncidentParams{UserID: userID.Int64, ID: incident.ID})
+			kind = monitoring.EventAcknowledged
+		}
+	case monitoring.UpdateResolved:
+		if incident.Status != monitoring.IncidentResolved {
+			_, err = q.ResolveIncident(ctx, db.ResolveIncidentParams{UserID: userID.Int64, ID: incident.ID})
+			kind = monitoring.EventResolved
+		}
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		kind, err = "", nil // Changed status in the meantime
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update incident"})
+		return
+	}
+	if kind != "" {
+		_, err := q.CreateIncidentEvent(ctx, db.CreateIncidentEventParams{
+			IncidentID: incident.ID,
+			Kind:       kind,
+			Message:    optionalText(input.Message),
+			UserID:     userID,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update incident timeline"})
+			return
+		}
+	}
+
+	update, err := q.CreateIncidentUpdate(ctx, db.CreateIncidentUpdateParams{
+		IncidentID: incident.ID,
+		Status:     input.Status,
+		Message:    input.Message,
+		UserID:     userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post update"})
+		return
+	}
+	if err := s.notifySubscribers(ctx, q, incident, update); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to notify subscribers"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post update"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, update)
+}
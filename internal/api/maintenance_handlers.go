@@ -0,0 +1,195 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/monitoring"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxMaintenanceDuration caps one-off maintenance windows.
+const maxMaintenanceDuration = 30 * 24 * time.Hour
+
+type maintenanceWindowInput struct {
+	Title           string    `json:"title" binding:"required,max=255"`
+	Description     string    `json:"description"`
+	ServiceIDs      []int64   `json:"service_ids" binding:"required,min=1,max=100,dive,required"`
+	StartsAt        time.Time `json:"starts_at" binding:"required"`
+	DurationMinutes int       `json:"duration_minutes" binding:"required,min=1"`
+
+	// Recurring windows only: a cron expression or an RRULE, the time zone
+	// it is evaluated in (UTC by default) and when it stops recurring
+	Recurrence      string     `json:"recurrence" binding:"max=255"`
+	Timezone        string     `json:"timezone" binding:"max=64"`
+	RecurrenceUntil *time.Time `json:"recurrence_until"`
+}
+
+// maintenanceWindowDetail is a maintenance window with the services it applies to.
+type maintenanceWindowDetail struct {
+	db.MaintenanceWindow
+	ServiceIDs []int64 `json:"service_ids"`
+}
+
+// validate checks the duration, time zone and recurrence of the window.
+func (in *maintenanceWindowInput) validate() error {
+	duration := time.Duration(in.DurationMinutes) * time.Minute
+	if in.Recurrence == "" {
+		if duration > maxMaintenanceDuration {
+			return fmt.Errorf("a maintenance window lasts at most %d days", int(maxMaintenanceDuration.Hours()/24))
+		}
+		if in.Timezone != "" || in.RecurrenceUntil != nil {
+			return fmt.Errorf("timezone and recurrence_until only apply to recurring windows")
+		}
+		return nil
+	}
+
+	if duration > monitoring.MaxRecurringMaintenance {
+		return fmt.Errorf("each occurrence of a recurring window lasts at most %d hours", int(monitoring.MaxRecurringMaintenance.Hours()))
+	}
+	if in.Timezone == "" {
+		in.Timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(in.Timezone)
+	if err != nil {
+		return fmt.Errorf("unknown time zone %q", in.Timezone)
+	}
+	if _, err := monitoring.ParseRecurrence(in.Recurrence, in.StartsAt.In(loc)); err != nil {
+		return fmt.Errorf("invalid recurrence: %w", err)
+	}
+	if in.RecurrenceUntil != nil && in.RecurrenceUntil.Before(in.StartsAt) {
+		return fmt.Errorf("recurrence_until must be after starts_at")
+	}
+	return nil
+}
+
+// createMaintenanceWindow schedules a one-off or recurring maintenance window
+// for some of the authenticated user's services.
+func (s *Server) createMaintenanceWindow(c *gin.Context) {
+	var input maintenanceWindowInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if err := input.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+	for _, serviceID := range input.ServiceIDs {
+		_, err := s.q.GetServiceForUser(ctx, db.GetServiceForUserParams{
+			ID:     serviceID,
+			UserID: userID,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Service %d not found", serviceID)})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve service"})
+			return
+		}
+	}
+
+	params := db.CreateMaintenanceWindowParams{
+		UserID:          userID,
+		Title:           input.Title,
+		Description:     optionalText(input.Description),
+		StartsAt:        pgtype.Timestamptz{Time: input.StartsAt, Valid: true},
+		DurationSeconds: int32(input.DurationMinutes * 60),
+		Recurrence:      optionalText(input.Recurrence),
+		Timezone:        "UTC",
+	}
+	if input.Recurrence != "" {
+		params.Timezone = input.Timezone
+	}
+	if input.RecurrenceUntil != nil {
+		params.RecurrenceUntil = pgtype.Timestamptz{Time: *input.RecurrenceUntil, Valid: true}
+	}
+
+	// The window and its services are created together
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create maintenance window"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	q := s.q.WithTx(tx)
+
+	window, err := q.CreateMaintenanceWindow(ctx, params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create maintenance window"})
+		return
+	}
+	detail := maintenanceWindowDetail{MaintenanceWindow: window, ServiceIDs: []int64{}}
+	seen := make(map[int64]bool)
+	for _, serviceID := range input.ServiceIDs {
+		if seen[serviceID] {
+			continue
+		}
+		seen[serviceID] = true
+		detail.ServiceIDs = append(detail.ServiceIDs, serviceID)
+		err := q.AddMaintenanceWindowService(ctx, db.AddMaintenanceWindowServiceParams{
+			WindowID:  window.ID,
+			ServiceID: serviceID,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create maintenance window"})
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create maintenance window"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, detail)
+}
+
+// getMaintenanceWindows lists the maintenance windows of the authenticated
+// user, including cancelled and past ones.
+func (s *Server) getMaintenanceWindows(c *gin.Context) {
+	windows, err := s.q.GetMaintenanceWindowsForUser(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve maintenance windows"})
+		return
+	}
+	if windows == nil {
+		windows = []db.GetMaintenanceWindowsForUserRow{}
+	}
+
+	c.JSON(http.StatusOK, windows)
+}
+
+// cancelMaintenanceWindow ends a maintenance window of the authenticated user
+// and all its future occurrences. It is kept for the record.
+func (s *Server) cancelMaintenanceWindow(c *gin.Context) {
+	windowID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid maintenance window ID"})
+		return
+	}
+
+	rowsAffected, err := s.q.CancelMaintenanceWindow(c.Request.Context(), db.CancelMaintenanceWindowParams{
+		ID:     windowID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel maintenance window"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Maintenance window not found or already cancelled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Maintenance window cancelled successfully"})
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getFailedNotifications lists the dead-lettered notifications of the
+// authenticated user's services.
+func (s *Server) getFailedNotifications(c *gin.Context) {
+	notifications, err := s.q.GetFailedNotificationsForUser(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notifications"})
+		return
+	}
+	if notifications == nil {
+		notifications = []db.GetFailedNotificationsForUserRow{}
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// retryNotification queues a dead-lettered notification for delivery again,
+// with a fresh set of attempts.
+func (s *Server) retryNotification(c *gin.Context) {
+	notificationID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	rowsAffected, err := s.q.RetryFailedNotification(c.Request.Context(), db.RetryFailedNotificationParams{
+		ID:     notificationID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry notification"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Failed notification not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification queued for delivery"})
+}
@@ -0,0 +1,374 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/notifications"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxCalendarDays caps how far ahead the iCalendar export goes.
+const maxCalendarDays = 365
+
+type scheduleInput struct {
+	Name        string   `json:"name" binding:"required,max=255"`
+	Rotation    string   `json:"rotation" binding:"required,oneof=daily weekly"`
+	Members     []string `json:"members" binding:"required,min=1,max=100,dive,required,email"`
+	Timezone    string   `json:"timezone" binding:"max=64"`       // UTC by default
+	StartsOn    string   `json:"starts_on" binding:"required"`    // YYYY-MM-DD, first shift of the first member
+	HandoffTime string   `json:"handoff_time" binding:"required"` // HH:MM in the time zone
+}
+
+type overrideInput struct {
+	Email    string    `json:"email" binding:"required,email"`
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+}
+
+// scheduleDetail is an on-call schedule with its handoff time formatted as
+// it was given.
+type scheduleDetail struct {
+	db.OncallSchedule
+	HandoffTime string `json:"handoff_time"`
+}
+
+func newScheduleDetail(schedule db.OncallSchedule) scheduleDetail {
+	handoff := time.Duration(schedule.HandoffTime.Microseconds) * time.Microsecond
+	return scheduleDetail{
+		OncallSchedule: schedule,
+		HandoffTime:    fmt.Sprintf("%02d:%02d", int(handoff.Hours()), int(handoff.Minutes())%60),
+	}
+}
+
+// createParams validates the input and converts it to query parameters.
+func (in *scheduleInput) createParams(userID int64) (db.CreateOncallScheduleParams, error) {
+	if in.Timezone == "" {
+		in.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(in.Timezone); err != nil {
+		return db.CreateOncallScheduleParams{}, fmt.Errorf("unknown time zone %q", in.Timezone)
+	}
+	startsOn, err := time.Parse(time.DateOnly, in.StartsOn)
+	if err != nil {
+		return db.CreateOncallScheduleParams{}, fmt.Errorf("starts_on must be a date like 2006-01-02")
+	}
+	handoff, err := time.Parse("15:04", in.HandoffTime)
+	if err != nil {
+		return db.CreateOncallScheduleParams{}, fmt.Errorf("handoff_time must be a time like 09:00")
+	}
+
+	return db.CreateOncallScheduleParams{
+		UserID:   userID,
+		Name:     in.Name,
+		Rotation: in.Rotation,
+		Members:  in.Members,
+		Timezone: in.Timezone,
+		StartsOn: pgtype.Date{Time: startsOn, Valid: true},
+		HandoffTime: pgtype.Time{
+			Microseconds: int64(handoff.Hour()*3600+handoff.Minute()*60) * 1e6,
+			Valid:        true,
+		},
+	}, nil
+}
+
+// scheduleParam loads the on-call schedule from the URL if it belongs to the
+// authenticated user. It writes the error response and returns false
+// otherwise.
+func (s *Server) scheduleParam(c *gin.Context) (db.OncallSchedule, bool) {
+	scheduleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return db.OncallSchedule{}, false
+	}
+
+	schedule, err := s.q.GetOncallScheduleForUser(c.Request.Context(), db.GetOncallScheduleForUserParams{
+		ID:     scheduleID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+			return db.OncallSchedule{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve schedule"})
+		return db.OncallSchedule{}, false
+	}
+	return schedule, true
+}
+
+// createSchedule creates an on-call schedule rotating daily or weekly through
+// its members.
+func (s *Server) createSchedule(c *gin.Context) {
+	var input scheduleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	params, err := input.createParams(c.GetInt64("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	schedule, err := s.q.CreateOncallSchedule(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newScheduleDetail(schedule))
+}
+
+// getSchedules lists the on-call schedules of the authenticated user.
+func (s *Server) getSchedules(c *gin.Context) {
+	schedules, err := s.q.GetOncallSchedulesForUser(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve schedules"})
+		return
+	}
+
+	details := make([]scheduleDetail, 0, len(schedules))
+	for _, schedule := range schedules {
+		details = append(details, newScheduleDetail(schedule))
+	}
+	c.JSON(http.StatusOK, details)
+}
+
+// deleteSchedule deletes an on-call schedule and its overrides. Services
+// using it go back to notifying their owner.
+func (s *Server) deleteSchedule(c *gin.Context) {
+	scheduleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+
+	rowsAffected, err := s.q.DeleteOncallSchedule(c.Request.Context(), db.DeleteOncallScheduleParams{
+		ID:     scheduleID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted successfully"})
+}
+
+// getOnCall returns who is on call for a schedule at ?at= (RFC 3339, now by
+// default), with the bounds of their shift. The email is null if nobody is.
+func (s *Server) getOnCall(c *gin.Context) {
+	schedule, ok := s.scheduleParam(c)
+	if !ok {
+		return
+	}
+
+	at := time.Now()
+	if param := c.Query("at"); param != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, param); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid at, expected an RFC 3339 time"})
+			return
+		}
+	}
+
+	rotation, err := notifications.LoadSchedule(c.Request.Context(), s.q, schedule, at, at.Add(time.Nanosecond))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute on-call shift"})
+		return
+	}
+
+	shift, ok := rotation.OnCallAt(at)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"schedule_id": schedule.ID, "at": at, "email": nil})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"schedule_id": schedule.ID,
+		"at":          at,
+		"email":       shift.Email,
+		"override":    shift.Override,
+		"shift_start": shift.Start,
+		"shift_end":   shift.End,
+	})
+}
+
+// getScheduleCalendar exports the shifts of a schedule as an iCalendar feed,
+// from a week ago to ?days= (90 by default) days ahead.
+func (s *Server) getScheduleCalendar(c *gin.Context) {
+	schedule, ok := s.scheduleParam(c)
+	if !ok {
+		return
+	}
+
+	days := 90
+	if param := c.Query("days"); param != "" {
+		var err error
+		if days, err = strconv.Atoi(param); err != nil || days < 1 || days > maxCalendarDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid days, must be between 1 and %d", maxCalendarDays)})
+			return
+		}
+	}
+
+	now := time.Now()
+	from, to := now.AddDate(0, 0, -7), now.AddDate(0, 0, days)
+	rotation, err := notifications.LoadSchedule(c.Request.Context(), s.q, schedule, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute on-call shifts"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="oncall-%d.ics"`, schedule.ID))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", notifications.ICalendar(schedule.ID, schedule.Name, rotation.Shifts(from, to)))
+}
+
+// createScheduleOverride puts someone on call instead of the rotation for a
+// period, e.g. to swap a shift. Later overrides take precedence.
+func (s *Server) createScheduleOverride(c *gin.Context) {
+	schedule, ok := s.scheduleParam(c)
+	if !ok {
+		return
+	}
+
+	var input overrideInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if !input.EndsAt.After(input.StartsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: ends_at must be after starts_at"})
+		return
+	}
+
+	override, err := s.q.CreateOncallOverride(c.Request.Context(), db.CreateOncallOverrideParams{
+		ScheduleID: schedule.ID,
+		Email:      input.Email,
+		StartsAt:   pgtype.Timestamptz{Time: input.StartsAt, Valid: true},
+		EndsAt:     pgtype.Timestamptz{Time: input.EndsAt, Valid: true},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create override"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, override)
+}
+
+// getScheduleOverrides lists the overrides of a schedule that have not ended.
+func (s *Server) getScheduleOverrides(c *gin.Context) {
+	schedule, ok := s.scheduleParam(c)
+	if !ok {
+		return
+	}
+
+	overrides, err := s.q.GetOncallOverrides(c.Request.Context(), db.GetOncallOverridesParams{
+		ScheduleID: schedule.ID,
+		RangeStart: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		RangeEnd:   pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve overrides"})
+		return
+	}
+	if overrides == nil {
+		overrides = []db.OncallOverride{}
+	}
+
+	c.JSON(http.StatusOK, overrides)
+}
+
+// deleteScheduleOverride removes an override from a schedule.
+func (s *Server) deleteScheduleOverride(c *gin.Context) {
+	schedule, ok := s.scheduleParam(c)
+	if !ok {
+		return
+	}
+	overrideID, err := strconv.ParseInt(c.Param("override_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid override ID"})
+		return
+	}
+
+	rowsAffected, err := s.q.DeleteOncallOverride(c.Request.Context(), db.DeleteOncallOverrideParams{
+		ID:         overrideID,
+		ScheduleID: schedule.ID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete override"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Override not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Override deleted successfully"})
+}
+
+// updateServiceOncallSchedule sets or, with a null oncall_schedule_id, clears
+// the schedule whose on-call person is notified instead of the owner when
+// the service has no notification channel.
+func (s *Server) updateServiceOncallSchedule(c *gin.Context) {
+	serviceID, ok := s.serviceParam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		OncallScheduleID *int64 `json:"oncall_schedule_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	scheduleID, ok := s.oncallScheduleID(c, input.OncallScheduleID)
+	if !ok {
+		return
+	}
+
+	_, err := s.q.SetServiceOncallSchedule(c.Request.Context(), db.SetServiceOncallScheduleParams{
+		OncallScheduleID: scheduleID,
+		ID:               serviceID,
+		UserID:           c.GetInt64("userID"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update on-call schedule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"oncall_schedule_id": input.OncallScheduleID})
+}
+
+// oncallScheduleID checks that the on-call schedule, if any, belongs to the
+// authenticated user. It writes the error response and returns false
+// otherwise.
+func (s *Server) oncallScheduleID(c *gin.Context, scheduleID *int64) (pgtype.Int8, bool) {
+	if scheduleID == nil {
+		return pgtype.Int8{}, true
+	}
+
+	_, err := s.q.GetOncallScheduleForUser(c.Request.Context(), db.GetOncallScheduleForUserParams{
+		ID:     *scheduleID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+			return pgtype.Int8{}, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve schedule"})
+		return pgtype.Int8{}, false
+	}
+	return pgtype.Int8{Int64: *scheduleID, Valid: true}, true
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"net/http"
+	"uptime-monitor/internal/config"
 	"uptime-monitor/internal/database/db"
 	"uptime-monitor/internal/web"
 
@@ -11,21 +13,25 @@ import (
 
 // Server now also holds web handlers
 type Server struct {
-	router *gin.Engine
-	db     *pgxpool.Pool
-	q      *db.Queries
+	router     *gin.Engine
+	httpServer *http.Server
+	db         *pgxpool.Pool
+	q          *db.Queries
+	cfg        *config.Config
 }
 
-func NewServer(dbPool *pgxpool.Pool) *Server {
+func NewServer(cfg *config.Config, dbPool *pgxpool.Pool) *Server {
 	server := &Server{
-		db: dbPool,
-		q:  db.New(dbPool),
+		db:  dbPool,
+		q:   db.New(dbPool),
+		cfg: cfg,
 	}
 	router := gin.Default()
 	server.router = router
+	server.httpServer = &http.Server{Handler: router}
 
 	// Pass the server instance to the web handlers
-	webHandlers := &web.Server{Q: server.q}
+	webHandlers := web.NewServer(server.q)
 
 	// --- STATIC FILES ---
 	router.StaticFS("/static", http.Dir("public"))
@@ -42,11 +48,45 @@ func NewServer(dbPool *pgxpool.Pool) *Server {
 		dashboardGroup.GET("/dashboard", webHandlers.ShowDashboardPage)
 	}
 
+	// Public status pages, by slug or at the root of their custom domain
+	router.GET("/status/:slug", webHandlers.ShowStatusPage)
+	router.GET("/", webHandlers.ShowStatusPageForDomain)
+
+	// Visitors subscribe to status pages; the links sent to them carry a token.
+	// Opening one only shows a form, like acknowledgement links.
+	router.POST("/status/:slug/subscribe", server.subscribeToStatusPage)
+	router.GET("/status/:slug/confirm", server.showSubscriptionConfirmation)
+	router.POST("/status/:slug/confirm", server.confirmStatusPageSubscription)
+	router.GET("/status/:slug/unsubscribe", server.showUnsubscribe)
+	router.POST("/status/:slug/unsubscribe", server.unsubscribeFromStatusPage)
+
 	// --- PUBLIC API ROUTES ---
 	router.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{"message": "pong"})
 	})
 
+	// Heartbeats are authenticated by the secret token in the URL
+	heartbeatRoutes := router.Group("/heartbeat")
+	{
+		heartbeatRoutes.GET("/:token", server.receiveHeartbeat)
+		heartbeatRoutes.POST("/:token", server.receiveHeartbeat)
+		heartbeatRoutes.GET("/:token/:kind", server.receiveHeartbeat)
+		heartbeatRoutes.POST("/:token/:kind", server.receiveHeartbeat)
+	}
+
+	// Probe agents authenticate with their own token
+	agentRoutes := router.Group("/agent")
+	agentRoutes.Use(server.agentAuthMiddleware())
+	{
+		agentRoutes.GET("/services", server.getAgentServices)
+		agentRoutes.POST("/results", server.ingestAgentResults)
+	}
+
+	// Acknowledgement links in alert emails are signed instead. Opening one
+	// only shows a form, so that link scanners don't acknowledge incidents.
+	router.GET("/incidents/:id/ack", server.showAckLink)
+	router.POST("/incidents/:id/ack", server.acknowledgeIncidentByLink)
+
 	authAPIRoutes := router.Group("/auth")
 	{
 		authAPIRoutes.POST("/register", server.registerUser)
@@ -55,20 +95,69 @@ func NewServer(dbPool *pgxpool.Pool) *Server {
 
 	// --- PROTECTED API ROUTES ---
 	apiRoutes := router.Group("/api")
-	apiRoutes.Use(AuthMiddleware()) // Note: This is the API middleware
+	apiRoutes.Use(authMiddleware()) // Note: This is the API middleware
 	{
 		apiRoutes.GET("/me", server.getMe)
 		apiRoutes.POST("/services", server.createService)
 		apiRoutes.GET("/services", server.getServices)
 		apiRoutes.DELETE("/services/:id", server.deleteService)
 		apiRoutes.GET("/services/:id/status", server.getServiceStatusHistory)
+		apiRoutes.GET("/services/:id/uptime", server.getServiceUptime)
+		apiRoutes.GET("/services/:id/dependencies", server.getServiceDependencies)
+		apiRoutes.PUT("/services/:id/dependencies", server.updateServiceDependencies)
+		apiRoutes.PUT("/services/:id/escalation-policy", server.updateServiceEscalationPolicy)
+		apiRoutes.PUT("/services/:id/oncall-schedule", server.updateServiceOncallSchedule)
+		apiRoutes.POST("/agents", server.createAgent)
+		apiRoutes.GET("/agents", server.getAgents)
+		apiRoutes.DELETE("/agents/:id", server.deleteAgent)
+		apiRoutes.POST("/notification-channels", server.createNotificationChannel)
+		apiRoutes.GET("/notification-channels", server.getNotificationChannels)
+		apiRoutes.DELETE("/notification-channels/:id", server.deleteNotificationChannel)
+		apiRoutes.GET("/notification-channels/:id/deliveries", server.getChannelDeliveries)
+		apiRoutes.GET("/notifications/failed", server.getFailedNotifications)
+		apiRoutes.POST("/notifications/:id/retry", server.retryNotification)
+		apiRoutes.GET("/email-templates", server.getEmailTemplates)
+		apiRoutes.PUT("/email-templates/:name", server.updateEmailTemplate)
+		apiRoutes.DELETE("/email-templates/:name", server.deleteEmailTemplate)
+		apiRoutes.GET("/incidents", server.getIncidents)
+		apiRoutes.POST("/incidents", server.createIncident)
+		apiRoutes.GET("/incidents/:id", server.getIncident)
+		apiRoutes.POST("/incidents/:id/acknowledge", server.acknowledgeIncident)
+		apiRoutes.POST("/incidents/:id/resolve", server.resolveIncident)
+		apiRoutes.POST("/incidents/:id/notes", server.addIncidentNote)
+		apiRoutes.POST("/incidents/:id/updates", server.postIncidentUpdate)
+		apiRoutes.POST("/maintenance-windows", server.createMaintenanceWindow)
+		apiRoutes.GET("/maintenance-windows", server.getMaintenanceWindows)
+		apiRoutes.DELETE("/maintenance-windows/:id", server.cancelMaintenanceWindow)
+		apiRoutes.POST("/escalation-policies", server.createEscalationPolicy)
+		apiRoutes.GET("/escalation-policies", server.getEscalationPolicies)
+		apiRoutes.DELETE("/escalation-policies/:id", server.deleteEscalationPolicy)
+		apiRoutes.POST("/schedules", server.createSchedule)
+		apiRoutes.GET("/schedules", server.getSchedules)
+		apiRoutes.DELETE("/schedules/:id", server.deleteSchedule)
+		apiRoutes.GET("/schedules/:id/oncall", server.getOnCall)
+		apiRoutes.GET("/schedules/:id/calendar.ics", server.getScheduleCalendar)
+		apiRoutes.POST("/schedules/:id/overrides", server.createScheduleOverride)
+		apiRoutes.GET("/schedules/:id/overrides", server.getScheduleOverrides)
+		apiRoutes.DELETE("/schedules/:id/overrides/:override_id", server.deleteScheduleOverride)
+		apiRoutes.POST("/status-pages", server.createStatusPage)
+		apiRoutes.GET("/status-pages", server.getStatusPages)
+		apiRoutes.DELETE("/status-pages/:id", server.deleteStatusPage)
+		apiRoutes.GET("/status-pages/:id/subscribers", server.getStatusPageSubscribers)
 	}
 
 	return server
 }
 
+// Start serves the API until Shutdown is called, in which case it returns http.ErrServerClosed.
 func (s *Server) Start(address string) error {
-	return s.router.Run(address)
+	s.httpServer.Addr = address
+	return s.httpServer.ListenAndServe()
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests until ctx expires.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
 }
 
 // getMe remains a method of the original Server struct for the API
@@ -1,18 +1,299 @@
 package api
 
 import (
-	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/monitoring"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type serviceInput struct {
 	Name                 string `json:"name" binding:"required"`
-	Target               string `json:"target" binding:"required,url"`
-	CheckIntervalSeconds int    `json:"check_interval_seconds" binding:"required,min=30"`
+	Type                 string `json:"type" binding:"omitempty,oneof=http tcp dns tls heartbeat"`
+	Target               string `json:"target" binding:"required_unless=Type heartbeat"`
+	CheckIntervalSeconds int    `json:"check_interval_seconds" binding:"required,min=10"`
+
+	// DNS services only
+	DNSRecordType string   `json:"dns_record_type" binding:"omitempty,oneof=A AAAA CNAME MX TXT NS"`
+	DNSResolver   string   `json:"dns_resolver" binding:"omitempty,hostname_port"`
+	DNSExpected   []string `json:"dns_expected"`
+
+	// Certificate checks (tls services, or https services with check_certificate)
+	CheckCertificate     bool    `json:"check_certificate"`
+	CertExpiryThresholds []int32 `json:"cert_expiry_thresholds" binding:"omitempty,dive,min=0"`
+
+	// HTTP services only
+	HTTPMethod       string            `json:"http_method" binding:"omitempty,oneof=GET HEAD POST PUT PATCH DELETE OPTIONS"`
+	HTTPHeaders      map[string]string `json:"http_headers"`
+	HTTPBody         string            `json:"http_body"`
+	HTTPAuthType     string            `json:"http_auth_type" binding:"omitempty,oneof=basic bearer"`
+	HTTPAuthUsername string            `json:"http_auth_username"`
+	HTTPAuthPassword string            `json:"http_auth_password"`
+	HTTPAuthToken    string            `json:"http_auth_token"`
+	FollowRedirects  *bool             `json:"follow_redirects"`
+
+	TimeoutSeconds int `json:"timeout_seconds" binding:"omitempty,min=1,max=60"`
+
+	// Response time thresholds above which the service is reported as degraded
+	DegradedThresholdMs    int `json:"degraded_threshold_ms" binding:"omitempty,min=1"`
+	DegradedP95ThresholdMs int `json:"degraded_p95_threshold_ms" binding:"omitempty,min=1"`
+	DegradedP95Window      int `json:"degraded_p95_window" binding:"omitempty,min=1,max=1000"`
+
+	// Confirmation before a state change is notified
+	RetriesBeforeDown    int `json:"retries_before_down" binding:"omitempty,min=0,max=10"`
+	SuccessesBeforeUp    int `json:"successes_before_up" binding:"omitempty,min=1,max=10"`
+	RetryIntervalSeconds int `json:"retry_interval_seconds" binding:"omitempty,min=5"`
+
+	// Flap detection: percent of state changes over the last checks above
+	// which per-transition alerts are suppressed; a threshold of 0 disables it
+	FlapWindow    int  `json:"flap_window" binding:"omitempty,min=5,max=100"`
+	FlapThreshold *int `json:"flap_threshold" binding:"omitempty,min=0,max=100"`
+
+	// Heartbeat services only: how late a ping may be before the service is down
+	HeartbeatGraceSeconds int `json:"heartbeat_grace_seconds" binding:"omitempty,min=0"`
+
+	// Response rules for HTTP services, see monitoring.Assertions
+	Assertions *monitoring.Assertions `json:"assertions"`
+
+	// Remote probe locations and how many of them must agree on a status
+	Locations    []string `json:"locations" binding:"omitempty,dive,required,max=100"`
+	Quorum       int      `json:"quorum" binding:"omitempty,min=1"`
+	CheckLocally *bool    `json:"check_locally"`
+
+	// Services this one depends on; while one is down, this one is
+	// unreachable and not notified separately
+	DependsOn []int64 `json:"depends_on" binding:"omitempty,max=50,dive,required"`
+
+	// Escalation policy notified instead of the channels when the service goes down
+	EscalationPolicyID *int64 `json:"escalation_policy_id"`
+
+	// On-call schedule notified instead of the owner when no channel applies
+	OncallScheduleID *int64 `json:"oncall_schedule_id"`
+}
+
+// optionalText converts an empty string to a NULL column value.
+func optionalText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
+
+// validateTarget checks that the target matches the format expected by the service type.
+func validateTarget(serviceType, target string) error {
+	switch serviceType {
+	case monitoring.TypeTCP:
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			return fmt.Errorf("target must be host:port for tcp services")
+		}
+	case monitoring.TypeDNS:
+		if strings.Contains(target, "/") || strings.Contains(target, ":") {
+			return fmt.Errorf("target must be a domain name for dns services")
+		}
+	case monitoring.TypeTLS:
+		if strings.Contains(target, "/") {
+			return fmt.Errorf("target must be host or host:port for tls services")
+		}
+	case monitoring.TypeHeartbeat:
+		// The target is replaced by the heartbeat URL
+	default:
+		u, err := url.ParseRequestURI(target)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("target must be a valid URL for http services")
+		}
+	}
+	return nil
+}
+
+// createParams converts the input into the parameters used to insert a service,
+// applying the defaults for each service type.
+func (in *serviceInput) createParams(userID int64) (db.CreateServiceParams, error) {
+	params := db.CreateServiceParams{
+		UserID:                userID,
+		Name:                  in.Name,
+		Target:                in.Target,
+		CheckIntervalSeconds:  int64(in.CheckIntervalSeconds),
+		Type:                  in.Type,
+		HttpMethod:            http.MethodGet,
+		HttpHeaders:           []byte("{}"),
+		Assertions:            []byte("{}"),
+		FollowRedirects:       true,
+		TimeoutSeconds:        10,
+		DegradedP95Window:     20,
+		RetriesBeforeDown:     int32(in.RetriesBeforeDown),
+		SuccessesBeforeUp:     1,
+		RetryIntervalSeconds:  15,
+		HeartbeatGraceSeconds: 60,
+		Locations:             []string{},
+		Quorum:                1,
+		CheckLocally:          true,
+		FlapWindow:            20,
+		FlapThreshold:         30,
+	}
+
+	if in.Type == monitoring.TypeDNS {
+		if in.DNSRecordType == "" {
+			in.DNSRecordType = "A"
+		}
+		params.DnsRecordType = optionalText(in.DNSRecordType)
+		params.DnsResolver = optionalText(in.DNSResolver)
+		params.DnsExpected = in.DNSExpected
+	}
+
+	params.CheckCertificate = in.CheckCertificate
+	params.CertExpiryThresholds = in.CertExpiryThresholds
+	if len(params.CertExpiryThresholds) == 0 {
+		params.CertExpiryThresholds = monitoring.DefaultCertExpiryThresholds
+	}
+
+	if in.Type == monitoring.TypeHTTP {
+		if in.HTTPMethod != "" {
+			params.HttpMethod = in.HTTPMethod
+		}
+		if len(in.HTTPHeaders) > 0 {
+			headers, err := json.Marshal(in.HTTPHeaders)
+			if err != nil {
+				return params, fmt.Errorf("invalid http_headers: %w", err)
+			}
+			params.HttpHeaders = headers
+		}
+		params.HttpBody = optionalText(in.HTTPBody)
+
+		switch in.HTTPAuthType {
+		case "basic":
+			if in.HTTPAuthUsername == "" {
+				return params, fmt.Errorf("http_auth_username is required for basic auth")
+			}
+			params.HttpAuthUsername = optionalText(in.HTTPAuthUsername)
+			params.HttpAuthPassword = optionalText(in.HTTPAuthPassword)
+		case "bearer":
+			if in.HTTPAuthToken == "" {
+				return params, fmt.Errorf("http_auth_token is required for bearer auth")
+			}
+			params.HttpAuthToken = optionalText(in.HTTPAuthToken)
+		}
+		params.HttpAuthType = optionalText(in.HTTPAuthType)
+
+		if in.FollowRedirects != nil {
+			params.FollowRedirects = *in.FollowRedirects
+		}
+
+		if in.Assertions != nil {
+			if err := in.Assertions.Validate(); err != nil {
+				return params, err
+			}
+			assertions, err := json.Marshal(in.Assertions)
+			if err != nil {
+				return params, fmt.Errorf("invalid assertions: %w", err)
+			}
+			params.Assertions = assertions
+		}
+	}
+
+	if in.TimeoutSeconds > 0 {
+		params.TimeoutSeconds = int32(in.TimeoutSeconds)
+	}
+
+	if in.DegradedThresholdMs > 0 {
+		params.DegradedThresholdMs = pgtype.Int4{Int32: int32(in.DegradedThresholdMs), Valid: true}
+	}
+	if in.DegradedP95ThresholdMs > 0 {
+		params.DegradedP95ThresholdMs = pgtype.Int4{Int32: int32(in.DegradedP95ThresholdMs), Valid: true}
+	}
+	if in.DegradedP95Window > 0 {
+		params.DegradedP95Window = int32(in.DegradedP95Window)
+	}
+
+	if in.Type == monitoring.TypeHeartbeat {
+		token, err := newToken()
+		if err != nil {
+			return params, fmt.Errorf("could not generate heartbeat token: %w", err)
+		}
+		params.HeartbeatToken = optionalText(token)
+		params.Target = "/heartbeat/" + token
+		if in.HeartbeatGraceSeconds > 0 {
+			params.HeartbeatGraceSeconds = int32(in.HeartbeatGraceSeconds)
+		}
+	}
+
+	if in.SuccessesBeforeUp > 0 {
+		params.SuccessesBeforeUp = int32(in.SuccessesBeforeUp)
+	}
+	if in.RetryIntervalSeconds > 0 {
+		params.RetryIntervalSeconds = int32(in.RetryIntervalSeconds)
+	}
+	if in.FlapWindow > 0 {
+		params.FlapWindow = int32(in.FlapWindow)
+	}
+	if in.FlapThreshold != nil {
+		params.FlapThreshold = int32(*in.FlapThreshold)
+	}
+
+	if err := in.locationParams(&params); err != nil {
+		return params, err
+	}
+
+	return params, nil
+}
+
+// locationParams validates the probe locations and quorum of the service.
+func (in *serviceInput) locationParams(params *db.CreateServiceParams) error {
+	if in.CheckLocally != nil {
+		params.CheckLocally = *in.CheckLocally
+	}
+	if len(in.Locations) == 0 {
+		if !params.CheckLocally {
+			return fmt.Errorf("check_locally can only be disabled when locations are set")
+		}
+		if in.Quorum > 1 {
+			return fmt.Errorf("quorum requires locations")
+		}
+		return nil
+	}
+	if in.Type == monitoring.TypeHeartbeat {
+		return fmt.Errorf("heartbeat services cannot be checked from locations")
+	}
+
+	seen := make(map[string]bool, len(in.Locations))
+	for _, location := range in.Locations {
+		if location == monitoring.LocationCentral {
+			return fmt.Errorf("location %q is reserved, use check_locally instead", location)
+		}
+		if !seen[location] {
+			seen[location] = true
+			params.Locations = append(params.Locations, location)
+		}
+	}
+
+	available := len(params.Locations)
+	if params.CheckLocally {
+		available++
+	}
+	if in.Quorum > available {
+		return fmt.Errorf("quorum of %d is more than the %d locations checking the service", in.Quorum, available)
+	}
+	if in.Quorum > 0 {
+		params.Quorum = int32(in.Quorum)
+	}
+	return nil
+}
+
+// redactService hides stored credentials before a service is sent to the client.
+func redactService(service *db.Service) {
+	if service.HttpAuthPassword.Valid {
+		service.HttpAuthPassword.String = "********"
+	}
+	if service.HttpAuthToken.Valid {
+		service.HttpAuthToken.String = "********"
+	}
 }
 
 // createService creates a new service for the authenticated user.
@@ -23,21 +304,64 @@ func (s *Server) createService(c *gin.Context) {
 		return
 	}
 
+	if input.Type == "" {
+		input.Type = monitoring.TypeHTTP
+	}
+	if err := validateTarget(input.Type, input.Target); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
 	userID := c.GetInt64("userID")
 
-	params := db.CreateServiceParams{
-		UserID:               userID,
-		Name:                 input.Name,
-		Target:               input.Target,
-		CheckIntervalSeconds: int64(input.CheckIntervalSeconds),
+	params, err := input.createParams(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
 	}
+	policyID, ok := s.escalationPolicyParam(c, input.EscalationPolicyID)
+	if !ok {
+		return
+	}
+	params.EscalationPolicyID = policyID
+	scheduleID, ok := s.oncallScheduleID(c, input.OncallScheduleID)
+	if !ok {
+		return
+	}
+	params.OncallScheduleID = scheduleID
 
-	service, err := s.q.CreateService(context.Background(), params)
+	// The service and its dependencies are created together
+	ctx := c.Request.Context()
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service"})
 		return
 	}
+	defer tx.Rollback(ctx)
+	q := s.q.WithTx(tx)
 
+	service, err := q.CreateService(ctx, params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service"})
+		return
+	}
+	if len(input.DependsOn) > 0 {
+		if _, err := setDependencies(ctx, q, userID, service.ID, input.DependsOn); err != nil {
+			var invalid *invalidDependencyError
+			if errors.As(err, &invalid) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + invalid.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service dependencies"})
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service"})
+		return
+	}
+
+	redactService(&service)
 	c.JSON(http.StatusCreated, service)
 }
 
@@ -45,7 +369,7 @@ func (s *Server) createService(c *gin.Context) {
 func (s *Server) getServices(c *gin.Context) {
 	userID := c.GetInt64("userID")
 
-	services, err := s.q.GetServicesForUser(context.Background(), userID)
+	services, err := s.q.GetServicesForUser(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve services"})
 		return
@@ -55,6 +379,9 @@ func (s *Server) getServices(c *gin.Context) {
 	if services == nil {
 		services = []db.Service{}
 	}
+	for i := range services {
+		redactService(&services[i])
+	}
 
 	c.JSON(http.StatusOK, services)
 }
@@ -75,7 +402,7 @@ func (s *Server) deleteService(c *gin.Context) {
 		UserID: userID,
 	}
 
-	rowsAffected, err := s.q.DeleteService(context.Background(), params)
+	rowsAffected, err := s.q.DeleteService(c.Request.Context(), params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete service"})
 		return
@@ -105,7 +432,7 @@ func (s *Server) getServiceStatusHistory(c *gin.Context) {
 		UserID:    userID, // Ensures the user owns the service
 	}
 
-	statusChecks, err := s.q.GetStatusChecksForService(context.Background(), params)
+	statusChecks, err := s.q.GetStatusChecksForService(c.Request.Context(), params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve status history"})
 		return
@@ -119,3 +446,56 @@ func (s *Server) getServiceStatusHistory(c *gin.Context) {
 
 	c.JSON(http.StatusOK, statusChecks)
 }
+
+// getServiceUptime returns the share of checks that were not down over the
+// last ?days=N days (30 by default). Checks during maintenance windows are
+// left out.
+func (s *Server) getServiceUptime(c *gin.Context) {
+	serviceID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+		return
+	}
+
+	_, err = s.q.GetServiceForUser(c.Request.Context(), db.GetServiceForUserParams{
+		ID:     serviceID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve service"})
+		return
+	}
+
+	uptime, err := s.q.GetUptimeForService(c.Request.Context(), db.GetUptimeForServiceParams{
+		ServiceID: serviceID,
+		CheckedAt: pgtype.Timestamptz{Time: time.Now().AddDate(0, 0, -days), Valid: true},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate uptime"})
+		return
+	}
+
+	// No checks outside maintenance: uptime is unknown
+	var percent *float64
+	if uptime.Checks > 0 {
+		p := float64(uptime.Checks-uptime.DownChecks) / float64(uptime.Checks) * 100
+		percent = &p
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":               days,
+		"checks":             uptime.Checks,
+		"down_checks":        uptime.DownChecks,
+		"maintenance_checks": uptime.MaintenanceChecks,
+		"uptime_percent":     percent,
+	})
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// slugPattern matches the slugs of status pages, served at /status/<slug>.
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type statusPageComponentInput struct {
+	ServiceID int64  `json:"service_id" binding:"required"`
+	Name      string `json:"name" binding:"max=255"` // The service name by default
+}
+
+type statusPageGroupInput struct {
+	Name       string                     `json:"name" binding:"required,max=255"`
+	Components []statusPageComponentInput `json:"components" binding:"required,min=1,max=50,dive"`
+}
+
+type statusPageInput struct {
+	Slug         string                 `json:"slug" binding:"required,max=64"`
+	CustomDomain string                 `json:"custom_domain" binding:"omitempty,fqdn,max=255"`
+	Title        string                 `json:"title" binding:"required,max=255"`
+	LogoURL      string                 `json:"logo_url" binding:"omitempty,url,max=2048"`
+	Description  string                 `json:"description" binding:"max=2000"`
+	Groups       []statusPageGroupInput `json:"groups" binding:"required,min=1,max=20,dive"`
+}
+
+// statusPageDetail is a status page with its components, in order.
+type statusPageDetail struct {
+	db.StatusPage
+	Components []db.GetStatusPageComponentsRow `json:"components"`
+}
+
+// createStatusPage creates a public status page showing some of the
+// authenticated user's services, grouped and in the given order.
+func (s *Server) createStatusPage(c *gin.Context) {
+	var input statusPageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if !slugPattern.MatchString(input.Slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: slug may only contain lowercase letters, digits and dashes"})
+		return
+	}
+	if input.LogoURL != "" && !strings.HasPrefix(input.LogoURL, "https://") && !strings.HasPrefix(input.LogoURL, "http://") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: logo_url must be an http(s) URL"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+	for _, group := range input.Groups {
+		for _, component := range group.Components {
+			_, err := s.q.GetServiceForUser(ctx, db.GetServiceForUserParams{
+				ID:     component.ServiceID,
+				UserID: userID,
+			})
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Service %d not found", component.ServiceID)})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve service"})
+				return
+			}
+		}
+	}
+
+	// The page and its components are created together
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create status page"})
+		return
+	}
+	defer tx.Rollback(ctx)
+	q := s.q.WithTx(tx)
+
+	page, err := q.CreateStatusPage(ctx, db.CreateStatusPageParams{
+		UserID:       userID,
+		Slug:         input.Slug,
+		CustomDomain: optionalText(strings.ToLower(input.CustomDomain)),
+		Title:        input.Title,
+		LogoUrl:      optionalText(input.LogoURL),
+		Description:  optionalText(input.Description),
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "unique constraint") {
+			c.JSON(http.StatusConflict, gin.H{"error": "Slug or custom domain already in use"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create status page"})
+		return
+	}
+
+	for i, groupInput := range input.Groups {
+		group, err := q.CreateStatusPageGroup(ctx, db.CreateStatusPageGroupParams{
+			PageID:   page.ID,
+			Name:     groupInput.Name,
+			Position: int32(i),
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create status page"})
+			return
+		}
+		for j, component := range groupInput.Components {
+			_, err := q.CreateStatusPageComponent(ctx, db.CreateStatusPageComponentParams{
+				GroupID:   group.ID,
+				ServiceID: component.ServiceID,
+				Name:      optionalText(component.Name),
+				Position:  int32(j),
+			})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create status page"})
+				return
+			}
+		}
+	}
+
+	components, err := q.GetStatusPageComponents(ctx, page.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create status page"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create status page"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, statusPageDetail{StatusPage: page, Components: components})
+}
+
+// getStatusPages lists the status pages of the authenticated user with their
+// components.
+func (s *Server) getStatusPages(c *gin.Context) {
+	ctx := c.Request.Context()
+	pages, err := s.q.GetStatusPagesForUser(ctx, c.GetInt64("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve status pages"})
+		return
+	}
+
+	details := make([]statusPageDetail, 0, len(pages))
+	for _, page := range pages {
+		components, err := s.q.GetStatusPageComponents(ctx, page.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve status pages"})
+			return
+		}
+		details = append(details, statusPageDetail{StatusPage: page, Components: components})
+	}
+
+	c.JSON(http.StatusOK, details)
+}
+
+// deleteStatusPage deletes a status page of the authenticated user.
+func (s *Server) deleteStatusPage(c *gin.Context) {
+	pageID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status page ID"})
+		return
+	}
+
+	rowsAffected, err := s.q.DeleteStatusPage(c.Request.Context(), db.DeleteStatusPageParams{
+		ID:     pageID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete status page"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Status page not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Status page deleted successfully"})
+}
@@ -0,0 +1,339 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/monitoring"
+	"uptime-monitor/internal/notifications"
+	"uptime-monitor/internal/web"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// subscribeInput is posted by the form on status pages or as JSON. Exactly
+// one of Email and WebhookURL is set.
+type subscribeInput struct {
+	Email        string  `form:"email" json:"email" binding:"omitempty,email,max=255"`
+	WebhookURL   string  `form:"webhook_url" json:"webhook_url" binding:"omitempty,url,max=2048"`
+	ComponentIDs []int64 `form:"component_ids" json:"component_ids" binding:"max=50"` // All components if empty
+}
+
+// statusPageParam looks up the status page with the slug in the URL. It
+// writes the error response and returns false if there is none.
+func (s *Server) statusPageParam(c *gin.Context) (db.StatusPage, bool) {
+	page, err := s.q.GetStatusPageBySlug(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Status page not found"})
+			return page, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve status page"})
+		return page, false
+	}
+	return page, true
+}
+
+// subscriberChannel is the outbox channel delivering to a status page subscriber.
+func subscriberChannel(subscriberID int64) db.NotificationChannel {
+	return db.NotificationChannel{Type: notifications.ChannelSubscriber, Target: strconv.FormatInt(subscriberID, 10)}
+}
+
+// subscribeToStatusPage subscribes a visitor to updates on the components of
+// a status page. Subscribers are sent a confirmation link and get nothing
+// else until they confirm: email subscribers open it, and webhooks receive a
+// subscription.confirm event and post to its confirm_url. Webhook subscribers
+// get the secret their requests are signed with in the response, to verify
+// that event.
+func (s *Server) subscribeToStatusPage(c *gin.Context) {
+	var input subscribeInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if (input.Email == "") == (input.WebhookURL == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: either email or webhook_url is required"})
+		return
+	}
+	if input.WebhookURL != "" && !strings.HasPrefix(input.WebhookURL, "https://") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: webhook_url must be an https URL"})
+		return
+	}
+	if s.cfg.PublicURL == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Subscriptions are not available"})
+		return
+	}
+
+	page, ok := s.statusPageParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if input.WebhookURL != "" {
+		if err := notifications.CheckPublicURL(ctx, input.WebhookURL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: webhook_url: " + err.Error()})
+			return
+		}
+	}
+
+	components, err := s.q.GetStatusPageComponents(ctx, page.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve status page"})
+		return
+	}
+	if len(components) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Status page has no components"})
+		return
+	}
+	onPage := make(map[int64]bool, len(components))
+	for _, component := range components {
+		onPage[component.ID] = true
+	}
+	for _, id := range input.ComponentIDs {
+		if !onPage[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid input: component %d is not on this status page", id)})
+			return
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	params := db.CreateStatusPageSubscriberParams{
+		PageID:       page.ID,
+		Type:         notifications.ChannelEmail,
+		Target:       input.Email,
+		ComponentIds: input.ComponentIDs,
+		Token:        token,
+	}
+	if params.ComponentIds == nil {
+		params.ComponentIds = []int64{}
+	}
+	if input.WebhookURL != "" {
+		secret, err := newToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+			return
+		}
+		params.Type = notifications.ChannelWebhook
+		params.Target = input.WebhookURL
+		params.Secret = pgtype.Text{String: secret, Valid: true}
+	}
+
+	subscriber, err := s.q.CreateStatusPageSubscriber(ctx, params)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe"})
+			return
+		}
+		if params.Type == notifications.ChannelWebhook {
+			c.JSON(http.StatusConflict, gin.H{"error": "Webhook is already subscribed to this status page"})
+			return
+		}
+		// The confirmation is only sent once per address, so that the form
+		// can't be used to flood an inbox. Nor does the response tell whether
+		// the address was subscribed already.
+		c.JSON(http.StatusAccepted, gin.H{"message": "Check your inbox to confirm the subscription"})
+		return
+	}
+
+	// The confirmation isn't about any service, so it's queued without one
+	channel := subscriberChannel(subscriber.ID)
+	alert := notifications.Alert{
+		Event:   notifications.EventSubscriptionConfirm,
+		Subject: "Confirm your subscription to " + page.Title,
+		Message: fmt.Sprintf("You asked to be notified of incidents on the %s status page. Confirm your subscription to start receiving updates. If you didn't ask, you can ignore this email.", page.Title),
+		Time:    time.Now(),
+	}
+	if subscriber.Type == notifications.ChannelWebhook {
+		alert.Message = fmt.Sprintf("This webhook was subscribed to incidents on the %s status page. Post to confirm_url to start receiving updates.", page.Title)
+	}
+	if err := notifications.Enqueue(ctx, s.q, []db.NotificationChannel{channel}, channel, alert, s.cfg.NotifyMaxAttempts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send confirmation"})
+		return
+	}
+
+	if subscriber.Type == notifications.ChannelWebhook {
+		// The links carry the token, so they are only sent to the webhook:
+		// whoever subscribed it can't confirm on its behalf.
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Post to the confirm_url sent to the webhook to confirm the subscription",
+			"secret":  subscriber.Secret.String,
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check your inbox to confirm the subscription"})
+}
+
+// subscriptionLinkParams looks up the status page and the subscriber whose
+// token is in a confirmation or unsubscribe link. It renders the error page
+// and returns false if there is none.
+func (s *Server) subscriptionLinkParams(c *gin.Context) (db.StatusPage, db.StatusPageSubscriber, bool) {
+	ctx := c.Request.Context()
+	page, err := s.q.GetStatusPageBySlug(ctx, c.Param("slug"))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			web.RenderLinkPage(c, http.StatusNotFound, web.LinkPage{Title: "Invalid link", Message: "Status page not found."})
+			return page, db.StatusPageSubscriber{}, false
+		}
+		web.RenderLinkPage(c, http.StatusInternalServerError, web.LinkPage{Title: "Subscription", Message: "Failed to retrieve status page."})
+		return page, db.StatusPageSubscriber{}, false
+	}
+
+	subscriber, err := s.q.GetStatusPageSubscriberByToken(ctx, db.GetStatusPageSubscriberByTokenParams{
+		PageID: page.ID,
+		Token:  c.Query("token"),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			web.RenderLinkPage(c, http.StatusNotFound, web.LinkPage{Title: "Invalid link", Message: "Invalid subscription link."})
+			return page, subscriber, false
+		}
+		web.RenderLinkPage(c, http.StatusInternalServerError, web.LinkPage{Title: "Subscription", Message: "Failed to retrieve subscription."})
+		return page, subscriber, false
+	}
+	return page, subscriber, true
+}
+
+// showSubscriptionConfirmation asks the subscriber who opened the link in a
+// confirmation message to confirm, without confirming anything yet.
+func (s *Server) showSubscriptionConfirmation(c *gin.Context) {
+	page, _, ok := s.subscriptionLinkParams(c)
+	if !ok {
+		return
+	}
+
+	web.RenderLinkPage(c, http.StatusOK, web.LinkPage{
+		Title:   "Confirm subscription",
+		Message: "Receive updates on incidents from the " + page.Title + " status page?",
+		Button:  "Confirm subscription",
+	})
+}
+
+// confirmStatusPageSubscription confirms a subscription from the form shown
+// by the link in the confirmation message. Webhook subscribers post to the
+// link directly.
+func (s *Server) confirmStatusPageSubscription(c *gin.Context) {
+	page, subscriber, ok := s.subscriptionLinkParams(c)
+	if !ok {
+		return
+	}
+
+	_, err := s.q.ConfirmStatusPageSubscriber(c.Request.Context(), db.ConfirmStatusPageSubscriberParams{
+		PageID: page.ID,
+		Token:  subscriber.Token,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			web.RenderLinkPage(c, http.StatusNotFound, web.LinkPage{Title: "Invalid link", Message: "Invalid subscription link."})
+			return
+		}
+		web.RenderLinkPage(c, http.StatusInternalServerError, web.LinkPage{Title: "Confirm subscription", Message: "Failed to confirm subscription."})
+		return
+	}
+
+	web.RenderLinkPage(c, http.StatusOK, web.LinkPage{Title: "Confirm subscription", Message: "Subscribed to updates from " + page.Title + "."})
+}
+
+// showUnsubscribe asks the subscriber who opened the unsubscribe link in a
+// message to confirm, without unsubscribing yet.
+func (s *Server) showUnsubscribe(c *gin.Context) {
+	page, _, ok := s.subscriptionLinkParams(c)
+	if !ok {
+		return
+	}
+
+	web.RenderLinkPage(c, http.StatusOK, web.LinkPage{
+		Title:   "Unsubscribe",
+		Message: "Stop receiving updates from the " + page.Title + " status page?",
+		Button:  "Unsubscribe",
+	})
+}
+
+// unsubscribeFromStatusPage cancels a subscription from the form shown by the
+// unsubscribe link in the messages sent to subscribers. Webhook subscribers
+// post to the link directly.
+func (s *Server) unsubscribeFromStatusPage(c *gin.Context) {
+	page, subscriber, ok := s.subscriptionLinkParams(c)
+	if !ok {
+		return
+	}
+
+	rowsAffected, err := s.q.DeleteStatusPageSubscriberByToken(c.Request.Context(), db.DeleteStatusPageSubscriberByTokenParams{
+		PageID: page.ID,
+		Token:  subscriber.Token,
+	})
+	if err != nil {
+		web.RenderLinkPage(c, http.StatusInternalServerError, web.LinkPage{Title: "Unsubscribe", Message: "Failed to unsubscribe."})
+		return
+	}
+	if rowsAffected == 0 {
+		web.RenderLinkPage(c, http.StatusNotFound, web.LinkPage{Title: "Invalid link", Message: "Invalid subscription link."})
+		return
+	}
+
+	web.RenderLinkPage(c, http.StatusOK, web.LinkPage{Title: "Unsubscribe", Message: "Unsubscribed from updates from " + page.Title + "."})
+}
+
+// getStatusPageSubscribers lists the subscribers of a status page of the
+// authenticated user.
+func (s *Server) getStatusPageSubscribers(c *gin.Context) {
+	pageID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status page ID"})
+		return
+	}
+
+	subscribers, err := s.q.GetStatusPageSubscribers(c.Request.Context(), db.GetStatusPageSubscribersParams{
+		PageID: pageID,
+		UserID: c.GetInt64("userID"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve subscribers"})
+		return
+	}
+	if subscribers == nil {
+		subscribers = []db.GetStatusPageSubscribersRow{}
+	}
+
+	c.JSON(http.StatusOK, subscribers)
+}
+
+// notifySubscribers queues the update for the confirmed subscribers of the
+// status pages showing the incident's service, with q bound to the
+// transaction that recorded it. Subscribers only see the names of the
+// service's components on their page.
+func (s *Server) notifySubscribers(ctx context.Context, q *db.Queries, incident db.GetIncidentForUserRow, update db.IncidentUpdate) error {
+	subscribers, err := q.GetStatusPageSubscribersForService(ctx, incident.ServiceID)
+	if err != nil {
+		return err
+	}
+
+	for _, subscriber := range subscribers {
+		channel := subscriberChannel(subscriber.ID)
+		alert := notifications.Alert{
+			Event:       notifications.EventIncidentUpdated,
+			ServiceID:   incident.ServiceID,
+			ServiceName: strings.Join(subscriber.ComponentNames, ", "),
+			Status:      update.Status,
+			Subject:     fmt.Sprintf("[%s] %s: %s", subscriber.PageTitle, monitoring.UpdateLabels[update.Status], incident.Title),
+			Message:     update.Message,
+			Time:        update.CreatedAt.Time,
+			IncidentID:  incident.ID,
+		}
+		if err := notifications.Enqueue(ctx, q, []db.NotificationChannel{channel}, channel, alert, s.cfg.NotifyMaxAttempts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
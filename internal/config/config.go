@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -10,13 +14,44 @@ type Config struct {
 	DatabaseURL   string
 	ServerAddress string
 	JWTSecret     string
+	PublicURL     string // Base URL of this server used in links sent by email, e.g. https://uptime.example.com
 
 	// SMTP configuration
 	SMTPHost     string
 	SMTPPort     string
 	SMTPUsername string
 	SMTPPassword string
+	SMTPSecurity string // "starttls" (default), "tls" for implicit TLS (port 465) or "none"
+	SMTPAuth     string // "plain" (default), "login", "cram-md5" or "none"
 	EmailSender  string
+
+	// Directory with subject.tmpl, body.txt.tmpl and body.html.tmpl overriding
+	// the built-in alert email templates; each file is optional
+	EmailTemplatesDir string
+
+	// Paging integrations; override to use the EU region or a test endpoint
+	PagerDutyEventsURL string
+	OpsgenieAPIURL     string
+
+	// Monitor scheduling
+	MonitorConcurrency     int           // Maximum number of checks running at once
+	MonitorJitter          time.Duration // Maximum random delay added to each scheduled check
+	MonitorRefreshInterval time.Duration // How often the service list is reloaded
+
+	// Notification outbox delivery
+	NotifyConcurrency  int           // Maximum number of deliveries running at once
+	NotifyPollInterval time.Duration // How often the outbox is polled for due notifications
+	NotifyMaxAttempts  int           // Attempts before a notification is dead-lettered
+
+	// Incident escalation
+	EscalationPollInterval time.Duration // How often incidents are checked for due escalation levels
+	AckLinkTTL             time.Duration // How long acknowledgement links in alert emails stay valid
+
+	// How long to wait for in-flight requests and checks on shutdown
+	ShutdownTimeout time.Duration
+
+	// Identifies this replica in check leases; defaults to hostname-pid
+	InstanceID string
 }
 
 // Load reads configuration from environment variables and returns a Config struct.
@@ -29,12 +64,137 @@ func Load() (*Config, error) {
 		DatabaseURL:   os.Getenv("DATABASE_URL"),
 		ServerAddress: os.Getenv("SERVER_ADDRESS"),
 		JWTSecret:     os.Getenv("JWT_SECRET"),
+		PublicURL:     strings.TrimRight(os.Getenv("PUBLIC_URL"), "/"),
 		SMTPHost:      os.Getenv("SMTP_HOST"),
 		SMTPPort:      os.Getenv("SMTP_PORT"),
 		SMTPUsername:  os.Getenv("SMTP_USERNAME"),
 		SMTPPassword:  os.Getenv("SMTP_PASSWORD"),
+		SMTPSecurity:  getEnv("SMTP_SECURITY", "starttls"),
+		SMTPAuth:      getEnv("SMTP_AUTH", "plain"),
 		EmailSender:   os.Getenv("EMAIL_SENDER"),
+
+		EmailTemplatesDir: os.Getenv("EMAIL_TEMPLATES_DIR"),
+		InstanceID:        os.Getenv("INSTANCE_ID"),
+
+		PagerDutyEventsURL: getEnv("PAGERDUTY_EVENTS_URL", "https://events.pagerduty.com/v2/enqueue"),
+		OpsgenieAPIURL:     getEnv("OPSGENIE_API_URL", "https://api.opsgenie.com"),
+	}
+
+	// The secret signs login tokens and acknowledgement links, which anyone
+	// could forge with an empty one
+	if cfg.JWTSecret == "" {
+		return nil, fmt.Errorf("JWT_SECRET is required")
+	}
+
+	if cfg.InstanceID == "" {
+		hostname, _ := os.Hostname()
+		cfg.InstanceID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	switch cfg.SMTPSecurity {
+	case "starttls", "tls", "none":
+	default:
+		return nil, fmt.Errorf("invalid SMTP_SECURITY %q: must be starttls, tls or none", cfg.SMTPSecurity)
+	}
+	switch cfg.SMTPAuth {
+	case "plain", "login", "cram-md5", "none":
+	default:
+		return nil, fmt.Errorf("invalid SMTP_AUTH %q: must be plain, login, cram-md5 or none", cfg.SMTPAuth)
+	}
+
+	var err error
+	if cfg.MonitorConcurrency, err = getEnvInt("MONITOR_CONCURRENCY", 50); err != nil {
+		return nil, err
+	}
+	if cfg.MonitorJitter, err = getEnvDuration("MONITOR_JITTER", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.MonitorRefreshInterval, err = getEnvDuration("MONITOR_REFRESH_INTERVAL", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.NotifyConcurrency, err = getEnvInt("NOTIFY_CONCURRENCY", 10); err != nil {
+		return nil, err
+	}
+	if cfg.NotifyPollInterval, err = getEnvDuration("NOTIFY_POLL_INTERVAL", 2*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.NotifyMaxAttempts, err = getEnvInt("NOTIFY_MAX_ATTEMPTS", 8); err != nil {
+		return nil, err
+	}
+	if cfg.EscalationPollInterval, err = getEnvDuration("ESCALATION_POLL_INTERVAL", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.AckLinkTTL, err = getEnvDuration("ACK_LINK_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.ShutdownTimeout, err = getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
+
+// AgentConfig is the configuration of a remote probe agent (cmd/agent).
+type AgentConfig struct {
+	APIURL string // Base URL of the central API, e.g. https://uptime.example.com
+	Token  string // Agent token returned when the agent was created
+
+	Concurrency     int           // Maximum number of checks running at once
+	RefreshInterval time.Duration // How often the assigned service list is pulled
+	PushInterval    time.Duration // How often buffered results are pushed
+}
+
+// LoadAgent reads the agent configuration from environment variables. A .env
+// file is optional since agents usually run outside the main deployment.
+func LoadAgent() (*AgentConfig, error) {
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	cfg := &AgentConfig{
+		APIURL: os.Getenv("AGENT_API_URL"),
+		Token:  os.Getenv("AGENT_TOKEN"),
+	}
+	if cfg.APIURL == "" || cfg.Token == "" {
+		return nil, fmt.Errorf("AGENT_API_URL and AGENT_TOKEN are required")
+	}
+
+	var err error
+	if cfg.Concurrency, err = getEnvInt("AGENT_CONCURRENCY", 20); err != nil {
+		return nil, err
+	}
+	if cfg.RefreshInterval, err = getEnvDuration("AGENT_REFRESH_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.PushInterval, err = getEnvDuration("AGENT_PUSH_INTERVAL", 10*time.Second); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// getEnv reads an environment variable, or returns def if it is unset.
+func getEnv(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// getEnvInt reads an integer environment variable, or returns def if it is unset.
+func getEnvInt(key string, def int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// getEnvDuration reads a duration environment variable (e.g. "30s"), or returns def if it is unset.
+func getEnvDuration(key string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	return time.ParseDuration(value)
+}
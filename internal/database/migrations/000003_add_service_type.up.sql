@@ -0,0 +1,3 @@
+-- +migrate Up
+ALTER TABLE "services"
+  ADD COLUMN "type" VARCHAR(20) NOT NULL DEFAULT 'http'; -- 'http' or 'tcp'
@@ -0,0 +1,5 @@
+-- +migrate Down
+ALTER TABLE "services"
+  DROP COLUMN IF EXISTS "dns_record_type",
+  DROP COLUMN IF EXISTS "dns_resolver",
+  DROP COLUMN IF EXISTS "dns_expected";
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE "services"
+  ADD COLUMN "dns_record_type" VARCHAR(10), -- 'A', 'AAAA', 'CNAME', 'MX', 'TXT' or 'NS'
+  ADD COLUMN "dns_resolver" VARCHAR(255),   -- ip:port, system resolver when NULL
+  ADD COLUMN "dns_expected" TEXT[];         -- expected answer set, any answer when NULL
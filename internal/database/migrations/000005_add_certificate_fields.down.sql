@@ -0,0 +1,9 @@
+-- +migrate Down
+ALTER TABLE "status_checks"
+  DROP COLUMN IF EXISTS "cert_days_remaining",
+  DROP COLUMN IF EXISTS "cert_issuer",
+  DROP COLUMN IF EXISTS "cert_sans";
+
+ALTER TABLE "services"
+  DROP COLUMN IF EXISTS "check_certificate",
+  DROP COLUMN IF EXISTS "cert_expiry_thresholds";
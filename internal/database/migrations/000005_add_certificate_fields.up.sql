@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE "services"
+  ADD COLUMN "check_certificate" BOOLEAN NOT NULL DEFAULT false, -- inspect the certificate of https targets
+  ADD COLUMN "cert_expiry_thresholds" INT[] NOT NULL DEFAULT '{30,14,7,1}'; -- days before expiry to alert at
+
+ALTER TABLE "status_checks"
+  ADD COLUMN "cert_days_remaining" INT,
+  ADD COLUMN "cert_issuer" TEXT,
+  ADD COLUMN "cert_sans" TEXT[];
@@ -0,0 +1,11 @@
+-- +migrate Down
+ALTER TABLE "services"
+  DROP COLUMN IF EXISTS "http_method",
+  DROP COLUMN IF EXISTS "http_headers",
+  DROP COLUMN IF EXISTS "http_body",
+  DROP COLUMN IF EXISTS "http_auth_type",
+  DROP COLUMN IF EXISTS "http_auth_username",
+  DROP COLUMN IF EXISTS "http_auth_password",
+  DROP COLUMN IF EXISTS "http_auth_token",
+  DROP COLUMN IF EXISTS "follow_redirects",
+  DROP COLUMN IF EXISTS "timeout_seconds";
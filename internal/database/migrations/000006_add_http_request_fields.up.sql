@@ -0,0 +1,11 @@
+-- +migrate Up
+ALTER TABLE "services"
+  ADD COLUMN "http_method" VARCHAR(10) NOT NULL DEFAULT 'GET',
+  ADD COLUMN "http_headers" JSONB NOT NULL DEFAULT '{}', -- {"Header-Name": "value"}
+  ADD COLUMN "http_body" TEXT,
+  ADD COLUMN "http_auth_type" VARCHAR(10), -- 'basic', 'bearer' or NULL
+  ADD COLUMN "http_auth_username" VARCHAR(255),
+  ADD COLUMN "http_auth_password" VARCHAR(255),
+  ADD COLUMN "http_auth_token" TEXT,
+  ADD COLUMN "follow_redirects" BOOLEAN NOT NULL DEFAULT true,
+  ADD COLUMN "timeout_seconds" INT NOT NULL DEFAULT 10;
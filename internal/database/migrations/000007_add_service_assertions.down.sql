@@ -0,0 +1,3 @@
+-- +migrate Down
+ALTER TABLE "services"
+  DROP COLUMN IF EXISTS "assertions";
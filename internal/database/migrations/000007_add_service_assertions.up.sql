@@ -0,0 +1,3 @@
+-- +migrate Up
+ALTER TABLE "services"
+  ADD COLUMN "assertions" JSONB NOT NULL DEFAULT '{}'; -- response rules, see monitoring.Assertions
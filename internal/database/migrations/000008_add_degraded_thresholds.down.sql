@@ -0,0 +1,10 @@
+-- +migrate Down
+UPDATE "status_checks" SET "status" = 'up' WHERE "status" = 'degraded';
+
+ALTER TABLE "status_checks"
+  ALTER COLUMN "status" TYPE VARCHAR(10);
+
+ALTER TABLE "services"
+  DROP COLUMN IF EXISTS "degraded_threshold_ms",
+  DROP COLUMN IF EXISTS "degraded_p95_threshold_ms",
+  DROP COLUMN IF EXISTS "degraded_p95_window";
@@ -0,0 +1,8 @@
+-- +migrate Up
+ALTER TABLE "services"
+  ADD COLUMN "degraded_threshold_ms" INT,     -- single check response time above which the service is degraded
+  ADD COLUMN "degraded_p95_threshold_ms" INT, -- rolling p95 response time above which the service is degraded
+  ADD COLUMN "degraded_p95_window" INT NOT NULL DEFAULT 20; -- number of checks in the rolling p95
+
+ALTER TABLE "status_checks"
+  ALTER COLUMN "status" TYPE VARCHAR(20); -- 'up', 'degraded' or 'down'
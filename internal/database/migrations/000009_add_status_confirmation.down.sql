@@ -0,0 +1,7 @@
+-- +migrate Down
+ALTER TABLE "services"
+  DROP COLUMN IF EXISTS "status",
+  DROP COLUMN IF EXISTS "status_changed_at",
+  DROP COLUMN IF EXISTS "retries_before_down",
+  DROP COLUMN IF EXISTS "successes_before_up",
+  DROP COLUMN IF EXISTS "retry_interval_seconds";
@@ -0,0 +1,14 @@
+-- +migrate Up
+ALTER TABLE "services"
+  ADD COLUMN "status" VARCHAR(20), -- confirmed status, NULL until the first check
+  ADD COLUMN "status_changed_at" TIMESTAMPTZ,
+  ADD COLUMN "retries_before_down" INT NOT NULL DEFAULT 0,    -- extra failed checks required before going down
+  ADD COLUMN "successes_before_up" INT NOT NULL DEFAULT 1,    -- successful checks required before going up
+  ADD COLUMN "retry_interval_seconds" INT NOT NULL DEFAULT 15; -- check interval while a state change is unconfirmed
+
+UPDATE "services" s SET "status" = (
+  SELECT sc."status" FROM "status_checks" sc
+  WHERE sc."service_id" = s."id"
+  ORDER BY sc."checked_at" DESC
+  LIMIT 1
+);
@@ -0,0 +1,14 @@
+-- +migrate Down
+ALTER TABLE "status_checks"
+  DROP COLUMN IF EXISTS "exit_code",
+  DROP COLUMN IF EXISTS "payload";
+
+ALTER TABLE "services"
+  DROP COLUMN IF EXISTS "heartbeat_token",
+  DROP COLUMN IF EXISTS "heartbeat_grace_seconds",
+  DROP COLUMN IF EXISTS "last_heartbeat_at",
+  DROP COLUMN IF EXISTS "last_heartbeat_status",
+  DROP COLUMN IF EXISTS "last_heartbeat_exit_code",
+  DROP COLUMN IF EXISTS "last_heartbeat_duration_ms",
+  DROP COLUMN IF EXISTS "last_heartbeat_payload",
+  DROP COLUMN IF EXISTS "heartbeat_started_at";
@@ -0,0 +1,16 @@
+-- +migrate Up
+ALTER TABLE "services"
+  ADD COLUMN "heartbeat_token" VARCHAR(64) UNIQUE, -- secret used in /heartbeat/:token
+  ADD COLUMN "heartbeat_grace_seconds" INT NOT NULL DEFAULT 60,
+  ADD COLUMN "last_heartbeat_at" TIMESTAMPTZ,
+  ADD COLUMN "last_heartbeat_status" VARCHAR(10), -- 'success' or 'fail'
+  ADD COLUMN "last_heartbeat_exit_code" INT,
+  ADD COLUMN "last_heartbeat_duration_ms" INT,    -- set when the run pinged /start first
+  ADD COLUMN "last_heartbeat_payload" TEXT,       -- log output sent with the last ping
+  ADD COLUMN "heartbeat_started_at" TIMESTAMPTZ;  -- set by /start until the run reports back
+
+-- Pings only update the service. The check recorded by the monitor at each
+-- interval carries the run they reported.
+ALTER TABLE "status_checks"
+  ADD COLUMN "exit_code" INT,
+  ADD COLUMN "payload" TEXT; -- log output sent with a heartbeat
@@ -0,0 +1,5 @@
+-- +migrate Down
+ALTER TABLE "services"
+  DROP COLUMN IF EXISTS "next_check_at",
+  DROP COLUMN IF EXISTS "locked_until",
+  DROP COLUMN IF EXISTS "locked_by";
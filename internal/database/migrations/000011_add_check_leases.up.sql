@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE "services"
+  ADD COLUMN "next_check_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  ADD COLUMN "locked_until" TIMESTAMPTZ, -- lease held by the instance checking the service
+  ADD COLUMN "locked_by" VARCHAR(255);
+
+CREATE INDEX ON "services" ("next_check_at");
@@ -0,0 +1,10 @@
+-- +migrate Down
+ALTER TABLE "status_checks"
+  DROP COLUMN IF EXISTS "location";
+
+ALTER TABLE "services"
+  DROP COLUMN IF EXISTS "locations",
+  DROP COLUMN IF EXISTS "quorum",
+  DROP COLUMN IF EXISTS "check_locally";
+
+DROP TABLE IF EXISTS "agents";
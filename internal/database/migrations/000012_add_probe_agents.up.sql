@@ -0,0 +1,22 @@
+-- +migrate Up
+CREATE TABLE "agents" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "user_id" BIGINT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "name" VARCHAR(255) NOT NULL,
+  "location" VARCHAR(100) NOT NULL,        -- probe location reported with every check
+  "token_hash" VARCHAR(64) UNIQUE NOT NULL, -- sha256 of the agent token
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "last_seen_at" TIMESTAMPTZ
+);
+
+CREATE INDEX ON "agents" ("user_id");
+
+ALTER TABLE "services"
+  ADD COLUMN "locations" TEXT[] NOT NULL DEFAULT '{}', -- agent locations that check the service
+  ADD COLUMN "quorum" INT NOT NULL DEFAULT 1,          -- locations that must agree before status changes
+  ADD COLUMN "check_locally" BOOLEAN NOT NULL DEFAULT true;
+
+ALTER TABLE "status_checks"
+  ADD COLUMN "location" VARCHAR(100) NOT NULL DEFAULT 'central';
+
+CREATE INDEX ON "status_checks" ("service_id", "location", "checked_at" DESC);
@@ -0,0 +1,3 @@
+-- +migrate Down
+DROP TABLE IF EXISTS "incident_events";
+DROP TABLE IF EXISTS "incidents";
@@ -0,0 +1,30 @@
+-- +migrate Up
+CREATE TABLE "incidents" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "service_id" BIGINT NOT NULL REFERENCES "services" ("id") ON DELETE CASCADE,
+  "status" VARCHAR(20) NOT NULL DEFAULT 'open', -- 'open', 'acknowledged' or 'resolved'
+  "title" VARCHAR(255) NOT NULL,
+  "first_error" TEXT,
+  "opened_by" BIGINT REFERENCES "users" ("id") ON DELETE SET NULL, -- NULL when opened by the monitor
+  "started_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "acknowledged_at" TIMESTAMPTZ,
+  "acknowledged_by" BIGINT REFERENCES "users" ("id") ON DELETE SET NULL,
+  "resolved_at" TIMESTAMPTZ,
+  "resolved_by" BIGINT REFERENCES "users" ("id") ON DELETE SET NULL, -- NULL when resolved by the monitor
+  "duration_seconds" BIGINT -- set when resolved
+);
+
+-- At most one unresolved incident per service
+CREATE UNIQUE INDEX "incidents_service_id_unresolved_idx" ON "incidents" ("service_id") WHERE "resolved_at" IS NULL;
+CREATE INDEX ON "incidents" ("service_id", "started_at" DESC);
+
+CREATE TABLE "incident_events" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "incident_id" BIGINT NOT NULL REFERENCES "incidents" ("id") ON DELETE CASCADE,
+  "kind" VARCHAR(20) NOT NULL, -- 'opened', 'acknowledged', 'resolved' or 'note'
+  "message" TEXT,
+  "user_id" BIGINT REFERENCES "users" ("id") ON DELETE SET NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "incident_events" ("incident_id", "created_at");
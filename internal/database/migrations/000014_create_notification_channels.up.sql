@@ -0,0 +1,14 @@
+-- +migrate Up
+CREATE TABLE "notification_channels" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "user_id" BIGINT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "service_id" BIGINT REFERENCES "services" ("id") ON DELETE CASCADE, -- NULL applies to all of the user's services
+  "type" VARCHAR(20) NOT NULL, -- 'email', 'slack', 'discord' or 'teams'
+  "name" VARCHAR(255) NOT NULL,
+  "target" TEXT NOT NULL,      -- email address or incoming webhook URL
+  "enabled" BOOLEAN NOT NULL DEFAULT true,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "notification_channels" ("user_id");
+CREATE INDEX ON "notification_channels" ("service_id");
@@ -0,0 +1,5 @@
+-- +migrate Down
+DROP TABLE IF EXISTS "webhook_deliveries";
+
+ALTER TABLE "notification_channels"
+  DROP COLUMN IF EXISTS "secret";
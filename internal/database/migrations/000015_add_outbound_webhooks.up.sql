@@ -0,0 +1,18 @@
+-- +migrate Up
+ALTER TABLE "notification_channels"
+  ADD COLUMN "secret" TEXT; -- HMAC key for webhook channels
+
+CREATE TABLE "webhook_deliveries" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "channel_id" BIGINT NOT NULL REFERENCES "notification_channels" ("id") ON DELETE CASCADE,
+  "event_id" VARCHAR(64) NOT NULL,   -- same for every attempt of an event
+  "event_type" VARCHAR(50) NOT NULL,
+  "attempt" INT NOT NULL,
+  "status_code" INT,
+  "error" TEXT,
+  "duration_ms" INT NOT NULL,
+  "success" BOOLEAN NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "webhook_deliveries" ("channel_id", "created_at" DESC);
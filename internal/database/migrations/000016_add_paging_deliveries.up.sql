@@ -0,0 +1,15 @@
+-- +migrate Up
+-- Trigger and resolve requests sent to PagerDuty and Opsgenie channels
+CREATE TABLE "paging_deliveries" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "channel_id" BIGINT NOT NULL REFERENCES "notification_channels" ("id") ON DELETE CASCADE,
+  "service_id" BIGINT NOT NULL REFERENCES "services" ("id") ON DELETE CASCADE,
+  "dedup_key" VARCHAR(255) NOT NULL, -- PagerDuty dedup_key or Opsgenie alias
+  "action" VARCHAR(20) NOT NULL,     -- 'trigger' or 'resolve'
+  "status_code" INT,
+  "error" TEXT,
+  "success" BOOLEAN NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "paging_deliveries" ("channel_id", "created_at" DESC);
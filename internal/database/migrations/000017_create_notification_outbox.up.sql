@@ -0,0 +1,21 @@
+-- +migrate Up
+CREATE TABLE "notification_outbox" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "service_id" BIGINT REFERENCES "services" ("id") ON DELETE CASCADE, -- NULL for notifications not about a service, such as subscription confirmations
+  "channel_id" BIGINT REFERENCES "notification_channels" ("id") ON DELETE CASCADE, -- NULL for the owner email fallback
+  "channel_type" VARCHAR(20) NOT NULL,
+  "target" TEXT NOT NULL,            -- used when channel_id is NULL
+  "alert" JSONB NOT NULL,            -- serialized notifications.Alert
+  "status" VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'delivered' or 'failed' (dead-lettered)
+  "attempts" INT NOT NULL DEFAULT 0,
+  "max_attempts" INT NOT NULL,
+  "next_attempt_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "last_error" TEXT,
+  "locked_until" TIMESTAMPTZ,        -- lease held by the dispatcher delivering the notification
+  "locked_by" VARCHAR(255),
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "delivered_at" TIMESTAMPTZ
+);
+
+CREATE INDEX ON "notification_outbox" ("next_attempt_at") WHERE "status" = 'pending';
+CREATE INDEX ON "notification_outbox" ("service_id", "created_at" DESC);
@@ -0,0 +1,6 @@
+-- +migrate Down
+ALTER TABLE "services"
+  DROP COLUMN IF EXISTS "flap_window",
+  DROP COLUMN IF EXISTS "flap_threshold",
+  DROP COLUMN IF EXISTS "flapping",
+  DROP COLUMN IF EXISTS "flapping_since";
@@ -0,0 +1,6 @@
+-- +migrate Up
+ALTER TABLE "services"
+  ADD COLUMN "flap_window" INT NOT NULL DEFAULT 20,    -- recent checks considered for flap detection
+  ADD COLUMN "flap_threshold" INT NOT NULL DEFAULT 30, -- percent of state changes in the window; 0 disables
+  ADD COLUMN "flapping" BOOLEAN NOT NULL DEFAULT false,
+  ADD COLUMN "flapping_since" TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- +migrate Down
+-- Checks during maintenance windows are neither up nor down, and 'maintenance'
+-- doesn't fit the status column once 000008 is rolled back
+DELETE FROM "status_checks" WHERE "status" = 'maintenance';
+
+DROP TABLE IF EXISTS "maintenance_window_services";
+DROP TABLE IF EXISTS "maintenance_windows";
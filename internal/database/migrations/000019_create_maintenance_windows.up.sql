@@ -0,0 +1,24 @@
+-- +migrate Up
+CREATE TABLE "maintenance_windows" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "user_id" BIGINT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "title" VARCHAR(255) NOT NULL,
+  "description" TEXT,
+  "starts_at" TIMESTAMPTZ NOT NULL,        -- start of the window, or of the first occurrence
+  "duration_seconds" INT NOT NULL,         -- length of the window or of each occurrence
+  "recurrence" TEXT,                       -- cron expression or RRULE; NULL for a one-off window
+  "timezone" VARCHAR(64) NOT NULL DEFAULT 'UTC', -- zone the recurrence is evaluated in
+  "recurrence_until" TIMESTAMPTZ,          -- no occurrence starts after this
+  "cancelled_at" TIMESTAMPTZ,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "maintenance_windows" ("user_id");
+
+CREATE TABLE "maintenance_window_services" (
+  "window_id" BIGINT NOT NULL REFERENCES "maintenance_windows" ("id") ON DELETE CASCADE,
+  "service_id" BIGINT NOT NULL REFERENCES "services" ("id") ON DELETE CASCADE,
+  PRIMARY KEY ("window_id", "service_id")
+);
+
+CREATE INDEX ON "maintenance_window_services" ("service_id");
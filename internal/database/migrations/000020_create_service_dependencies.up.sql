@@ -0,0 +1,11 @@
+-- +migrate Up
+-- A service is unreachable rather than down while one of the services it
+-- depends on is down.
+CREATE TABLE "service_dependencies" (
+  "service_id" BIGINT NOT NULL REFERENCES "services" ("id") ON DELETE CASCADE,
+  "depends_on_id" BIGINT NOT NULL REFERENCES "services" ("id") ON DELETE CASCADE,
+  PRIMARY KEY ("service_id", "depends_on_id"),
+  CHECK ("service_id" <> "depends_on_id")
+);
+
+CREATE INDEX ON "service_dependencies" ("depends_on_id");
@@ -0,0 +1,10 @@
+-- +migrate Down
+ALTER TABLE "incidents"
+  DROP COLUMN IF EXISTS "escalation_level",
+  DROP COLUMN IF EXISTS "next_escalation_at";
+
+ALTER TABLE "services"
+  DROP COLUMN IF EXISTS "escalation_policy_id";
+
+DROP TABLE IF EXISTS "escalation_levels";
+DROP TABLE IF EXISTS "escalation_policies";
@@ -0,0 +1,28 @@
+-- +migrate Up
+CREATE TABLE "escalation_policies" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "user_id" BIGINT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "name" VARCHAR(255) NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "escalation_policies" ("user_id");
+
+CREATE TABLE "escalation_levels" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "policy_id" BIGINT NOT NULL REFERENCES "escalation_policies" ("id") ON DELETE CASCADE,
+  "position" INT NOT NULL,                     -- 0 for the first level
+  "delay_minutes" INT NOT NULL,                -- after the incident opened
+  "channel_ids" BIGINT[] NOT NULL DEFAULT '{}', -- notification channels of the policy owner
+  "emails" TEXT[] NOT NULL DEFAULT '{}',
+  UNIQUE ("policy_id", "position")
+);
+
+ALTER TABLE "services"
+  ADD COLUMN "escalation_policy_id" BIGINT REFERENCES "escalation_policies" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "incidents"
+  ADD COLUMN "escalation_level" INT NOT NULL DEFAULT 0, -- levels notified so far
+  ADD COLUMN "next_escalation_at" TIMESTAMPTZ;          -- NULL once there is no level left
+
+CREATE INDEX ON "incidents" ("next_escalation_at") WHERE "status" = 'open';
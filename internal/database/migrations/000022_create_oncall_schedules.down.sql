@@ -0,0 +1,6 @@
+-- +migrate Down
+ALTER TABLE "services"
+  DROP COLUMN IF EXISTS "oncall_schedule_id";
+
+DROP TABLE IF EXISTS "oncall_overrides";
+DROP TABLE IF EXISTS "oncall_schedules";
@@ -0,0 +1,31 @@
+-- +migrate Up
+CREATE TABLE "oncall_schedules" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "user_id" BIGINT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "name" VARCHAR(255) NOT NULL,
+  "rotation" VARCHAR(16) NOT NULL,                -- 'daily' or 'weekly'
+  "members" TEXT[] NOT NULL,                      -- emails, on call in this order
+  "timezone" VARCHAR(64) NOT NULL DEFAULT 'UTC',  -- zone of the handoff time
+  "starts_on" DATE NOT NULL,                      -- first member's first shift; weekly handoffs are on this weekday
+  "handoff_time" TIME NOT NULL,                   -- local time shifts change
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "oncall_schedules" ("user_id");
+
+CREATE TABLE "oncall_overrides" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "schedule_id" BIGINT NOT NULL REFERENCES "oncall_schedules" ("id") ON DELETE CASCADE,
+  "email" TEXT NOT NULL,                          -- on call instead of the rotation
+  "starts_at" TIMESTAMPTZ NOT NULL,
+  "ends_at" TIMESTAMPTZ NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  CHECK ("ends_at" > "starts_at")
+);
+
+CREATE INDEX ON "oncall_overrides" ("schedule_id", "ends_at");
+
+-- Whoever is on call is notified instead of the owner when the service has
+-- no notification channel
+ALTER TABLE "services"
+  ADD COLUMN "oncall_schedule_id" BIGINT REFERENCES "oncall_schedules" ("id") ON DELETE SET NULL;
@@ -0,0 +1,5 @@
+-- +migrate Down
+DROP TABLE IF EXISTS "status_changes";
+DROP TABLE IF EXISTS "status_page_components";
+DROP TABLE IF EXISTS "status_page_groups";
+DROP TABLE IF EXISTS "status_pages";
@@ -0,0 +1,51 @@
+-- +migrate Up
+CREATE TABLE "status_pages" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "user_id" BIGINT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "slug" VARCHAR(64) UNIQUE NOT NULL,         -- served at /status/<slug>
+  "custom_domain" VARCHAR(255) UNIQUE,        -- also served at the root of this host
+  "title" VARCHAR(255) NOT NULL,
+  "logo_url" TEXT,
+  "description" TEXT,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "status_pages" ("user_id");
+
+CREATE TABLE "status_page_groups" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "page_id" BIGINT NOT NULL REFERENCES "status_pages" ("id") ON DELETE CASCADE,
+  "name" VARCHAR(255) NOT NULL,
+  "position" INT NOT NULL
+);
+
+CREATE INDEX ON "status_page_groups" ("page_id");
+
+CREATE TABLE "status_page_components" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "group_id" BIGINT NOT NULL REFERENCES "status_page_groups" ("id") ON DELETE CASCADE,
+  "service_id" BIGINT NOT NULL REFERENCES "services" ("id") ON DELETE CASCADE,
+  "name" VARCHAR(255),                        -- shown instead of the service name
+  "position" INT NOT NULL
+);
+
+CREATE INDEX ON "status_page_components" ("group_id");
+CREATE INDEX ON "status_page_components" ("service_id");
+
+-- The confirmed statuses of each service over time, for uptime reporting.
+-- "maintenance" marks the start of a maintenance window, after which the
+-- confirmed status is recorded again.
+CREATE TABLE "status_changes" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "service_id" BIGINT NOT NULL REFERENCES "services" ("id") ON DELETE CASCADE,
+  "status" VARCHAR(20) NOT NULL, -- 'up', 'degraded', 'down', 'unreachable' or 'maintenance'
+  "changed_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "status_changes" ("service_id", "changed_at" DESC);
+
+-- Earlier changes are unknown, so history starts at the current status
+INSERT INTO "status_changes" ("service_id", "status", "changed_at")
+SELECT "id", "status", COALESCE("status_changed_at", "created_at")
+FROM "services"
+WHERE "status" IS NOT NULL;
@@ -0,0 +1,3 @@
+-- +migrate Down
+DROP TABLE IF EXISTS "incident_updates";
+DROP TABLE IF EXISTS "status_page_subscribers";
@@ -0,0 +1,25 @@
+-- +migrate Up
+CREATE TABLE "status_page_subscribers" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "page_id" BIGINT NOT NULL REFERENCES "status_pages" ("id") ON DELETE CASCADE,
+  "type" VARCHAR(20) NOT NULL,                    -- 'email' or 'webhook'
+  "target" TEXT NOT NULL,                         -- email address or webhook URL
+  "component_ids" BIGINT[] NOT NULL DEFAULT '{}', -- empty for all components of the page
+  "token" VARCHAR(64) UNIQUE NOT NULL,            -- in confirmation and unsubscribe links
+  "secret" VARCHAR(255),                          -- signs webhook requests
+  "confirmed_at" TIMESTAMPTZ,                     -- NULL until the subscriber confirms with the link sent to them
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  UNIQUE ("page_id", "type", "target")
+);
+
+-- Updates on incidents posted by operators for status page visitors
+CREATE TABLE "incident_updates" (
+  "id" BIGSERIAL PRIMARY KEY,
+  "incident_id" BIGINT NOT NULL REFERENCES "incidents" ("id") ON DELETE CASCADE,
+  "status" VARCHAR(20) NOT NULL, -- 'investigating', 'identified', 'monitoring' or 'resolved'
+  "message" TEXT NOT NULL,
+  "user_id" BIGINT REFERENCES "users" ("id") ON DELETE SET NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "incident_updates" ("incident_id", "created_at");
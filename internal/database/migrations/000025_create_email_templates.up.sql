@@ -0,0 +1,10 @@
+-- +migrate Up
+-- Alert email templates overridden by a user. Templates they haven't
+-- overridden are the built-in ones.
+CREATE TABLE "email_templates" (
+  "user_id" BIGINT NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "name" VARCHAR(32) NOT NULL, -- 'subject.tmpl', 'body.txt.tmpl' or 'body.html.tmpl'
+  "content" TEXT NOT NULL,
+  "updated_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("user_id", "name")
+);
@@ -9,8 +9,20 @@ FROM users
 WHERE email = $1;
 
 -- name: CreateService :one
-INSERT INTO services (user_id, name, target, check_interval_seconds)
-VALUES ($1, $2, $3, $4)
+INSERT INTO services (
+  user_id, name, target, check_interval_seconds, type,
+  dns_record_type, dns_resolver, dns_expected,
+  check_certificate, cert_expiry_thresholds,
+  http_method, http_headers, http_body, http_auth_type, http_auth_username, http_auth_password, http_auth_token,
+  follow_redirects, timeout_seconds, assertions,
+  degraded_threshold_ms, degraded_p95_threshold_ms, degraded_p95_window,
+  retries_before_down, successes_before_up, retry_interval_seconds,
+  heartbeat_token, heartbeat_grace_seconds,
+  locations, quorum, check_locally,
+  flap_window, flap_threshold,
+  escalation_policy_id, oncall_schedule_id
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35)
 RETURNING *;
 
 -- name: GetServicesAndOwners :many
@@ -18,13 +30,34 @@ SELECT s.*, u.email as owner_email
 FROM services s
 JOIN users u ON s.user_id = u.id;
 
+-- name: GetServicesForUser :many
+SELECT * FROM services
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetServiceByHeartbeatToken :one
+SELECT * FROM services
+WHERE heartbeat_token = $1 AND type = 'heartbeat';
+
+-- name: StartHeartbeat :exec
+UPDATE services
+SET heartbeat_started_at = now()
+WHERE id = $1;
+
+-- name: RecordHeartbeat :exec
+UPDATE services
+SET last_heartbeat_at = now(), last_heartbeat_status = $2,
+    last_heartbeat_exit_code = $3, last_heartbeat_duration_ms = $4, last_heartbeat_payload = $5,
+    heartbeat_started_at = NULL
+WHERE id = $1;
+
 -- name: DeleteService :execrows
 DELETE FROM services
 WHERE id = $1 AND user_id = $2;
 
 -- name: CreateStatusCheck :one
-INSERT INTO status_checks (service_id, status, status_code, response_time_ms, error_message)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO status_checks (service_id, status, status_code, response_time_ms, error_message, cert_days_remaining, cert_issuer, cert_sans, exit_code, payload, location)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 RETURNING *;
 
 -- name: GetStatusChecksForService :many
@@ -35,8 +68,700 @@ WHERE sc.service_id = $1 AND s.user_id = $2
 ORDER BY sc.checked_at DESC
 LIMIT 50;
 
--- name: GetLatestStatusCheckForService :one
+-- name: GetRecentStatusesForService :many
 SELECT status FROM status_checks
-WHERE service_id = $1
+WHERE service_id = $1 AND status <> 'maintenance'
+ORDER BY checked_at DESC
+LIMIT $2;
+
+-- name: GetRecentCheckStatuses :many
+-- Statuses of the latest checks from one location, newest first.
+SELECT status FROM status_checks
+WHERE service_id = $1 AND location = $2 AND status <> 'maintenance'
+ORDER BY checked_at DESC
+LIMIT $3;
+
+-- name: SetServiceFlapping :execrows
+-- Only one instance can record the start or end of flapping, so only it notifies.
+UPDATE services
+SET flapping = $2,
+    flapping_since = CASE WHEN $2 THEN now() END
+WHERE id = $1 AND flapping <> $2;
+
+-- name: UpdateServiceStatus :execrows
+-- Only one instance can record a given state change, so only it notifies.
+UPDATE services
+SET status = $2, status_changed_at = now()
+WHERE id = $1 AND status IS DISTINCT FROM $2;
+
+-- name: CreateStatusChange :exec
+INSERT INTO status_changes (service_id, status)
+VALUES ($1, $2);
+
+-- name: StartMaintenanceStatus :exec
+-- Marks the start of maintenance, unless it is already under way.
+INSERT INTO status_changes (service_id, status)
+SELECT sqlc.arg(service_id)::bigint, 'maintenance'
+WHERE (
+  SELECT status FROM status_changes
+  WHERE service_id = sqlc.arg(service_id)::bigint
+  ORDER BY changed_at DESC, id DESC
+  LIMIT 1
+) IS DISTINCT FROM 'maintenance';
+
+-- name: EndMaintenanceStatus :exec
+-- Records the confirmed status again once maintenance is over.
+INSERT INTO status_changes (service_id, status)
+SELECT s.id, s.status FROM services s
+WHERE s.id = $1 AND s.status IS NOT NULL AND (
+  SELECT status FROM status_changes
+  WHERE service_id = s.id
+  ORDER BY changed_at DESC, id DESC
+  LIMIT 1
+) = 'maintenance';
+
+-- name: ClaimServiceCheck :one
+-- Takes the lease on a due service so no other instance checks it concurrently,
+-- and moves its next check forward by one interval.
+WITH claimed AS (
+  UPDATE services
+  SET next_check_at = now() + check_interval_seconds * interval '1 second',
+      locked_until = now() + sqlc.arg(lease_seconds)::int * interval '1 second',
+      locked_by = sqlc.arg(instance_id)::text
+  WHERE services.id = sqlc.arg(id)
+    AND next_check_at <= now()
+    AND (locked_until IS NULL OR locked_until < now())
+  RETURNING *
+)
+SELECT claimed.*, u.email as owner_email
+FROM claimed
+JOIN users u ON claimed.user_id = u.id;
+
+-- name: ReleaseServiceCheck :exec
+UPDATE services
+SET locked_until = NULL, locked_by = NULL
+WHERE id = $1 AND locked_by = $2;
+
+-- name: RescheduleServiceCheck :exec
+-- Brings the next check forward, e.g. to retry an unconfirmed state change.
+UPDATE services
+SET next_check_at = $2
+WHERE id = $1 AND next_check_at > $2;
+
+-- name: GetLatestCertDaysForService :one
+SELECT cert_days_remaining FROM status_checks
+WHERE service_id = $1 AND cert_days_remaining IS NOT NULL
 ORDER BY checked_at DESC
 LIMIT 1;
+
+-- name: GetRecentResponseTimesForService :many
+SELECT response_time_ms FROM status_checks
+WHERE service_id = $1 AND response_time_ms IS NOT NULL AND location = 'central' AND status <> 'maintenance'
+ORDER BY checked_at DESC
+LIMIT $2;
+
+-- name: GetLatestStatusPerLocation :many
+SELECT DISTINCT ON (location) location, status
+FROM status_checks
+WHERE service_id = $1 AND checked_at > $2 AND status <> 'maintenance'
+ORDER BY location, checked_at DESC;
+
+-- name: CreateAgent :one
+INSERT INTO agents (user_id, name, location, token_hash)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetAgentsForUser :many
+SELECT * FROM agents
+WHERE user_id = $1
+ORDER BY location, name;
+
+-- name: DeleteAgent :execrows
+DELETE FROM agents
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetAgentByTokenHash :one
+SELECT * FROM agents
+WHERE token_hash = $1;
+
+-- name: TouchAgent :exec
+UPDATE agents
+SET last_seen_at = now()
+WHERE id = $1;
+
+-- name: GetServicesForAgent :many
+-- Services of the agent's owner that are assigned to the agent's location.
+SELECT s.*, u.email as owner_email
+FROM services s
+JOIN users u ON s.user_id = u.id
+WHERE s.user_id = sqlc.arg(user_id)
+  AND sqlc.arg(location)::text = ANY(s.locations)
+  AND s.type <> 'heartbeat';
+
+-- name: OpenIncident :one
+-- Returns no rows if the service already has an unresolved incident.
+INSERT INTO incidents (service_id, title, first_error, opened_by)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (service_id) WHERE resolved_at IS NULL DO NOTHING
+RETURNING *;
+
+-- name: GetUnresolvedIncidentForService :one
+SELECT * FROM incidents
+WHERE service_id = $1 AND resolved_at IS NULL;
+
+-- name: ResolveServiceIncident :one
+-- Resolves the unresolved incident the monitor opened for a service, if any.
+UPDATE incidents
+SET status = 'resolved',
+    resolved_at = now(),
+    duration_seconds = EXTRACT(EPOCH FROM now() - started_at)::bigint
+WHERE service_id = $1 AND resolved_at IS NULL AND opened_by IS NULL
+RETURNING *;
+
+-- name: GetIncidentsForUser :many
+SELECT i.*, s.name as service_name
+FROM incidents i
+JOIN services s ON i.service_id = s.id
+WHERE s.user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(status)::text IS NULL OR i.status = sqlc.narg(status)::text)
+ORDER BY i.started_at DESC
+LIMIT 100;
+
+-- name: GetIncidentForUser :one
+SELECT i.*, s.name as service_name
+FROM incidents i
+JOIN services s ON i.service_id = s.id
+WHERE i.id = $1 AND s.user_id = $2;
+
+-- name: AcknowledgeIncident :one
+UPDATE incidents i
+SET status = 'acknowledged', acknowledged_at = now(), acknowledged_by = sqlc.arg(user_id)::bigint
+FROM services s
+WHERE i.id = sqlc.arg(id) AND i.service_id = s.id AND s.user_id = sqlc.arg(user_id)
+  AND i.status = 'open'
+RETURNING i.*;
+
+-- name: ResolveIncident :one
+UPDATE incidents i
+SET status = 'resolved',
+    resolved_at = now(),
+    resolved_by = sqlc.arg(user_id)::bigint,
+    duration_seconds = EXTRACT(EPOCH FROM now() - i.started_at)::bigint
+FROM services s
+WHERE i.id = sqlc.arg(id) AND i.service_id = s.id AND s.user_id = sqlc.arg(user_id)
+  AND i.resolved_at IS NULL
+RETURNING i.*;
+
+-- name: CreateIncidentEvent :one
+INSERT INTO incident_events (incident_id, kind, message, user_id)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetIncidentEvents :many
+SELECT * FROM incident_events
+WHERE incident_id = $1
+ORDER BY created_at, id;
+
+-- name: GetServiceForUser :one
+SELECT * FROM services
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateNotificationChannel :one
+INSERT INTO notification_channels (user_id, service_id, type, name, target, enabled, secret)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetNotificationChannelsForUser :many
+SELECT * FROM notification_channels
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: DeleteNotificationChannel :execrows
+DELETE FROM notification_channels
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetNotificationChannelsForService :many
+-- Enabled channels of the service owner that apply to the service.
+SELECT nc.* FROM notification_channels nc
+JOIN services s ON nc.user_id = s.user_id
+WHERE s.id = $1 AND nc.enabled
+  AND (nc.service_id IS NULL OR nc.service_id = s.id)
+ORDER BY nc.id;
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (channel_id, event_id, event_type, attempt, status_code, error, duration_ms, success)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: GetWebhookDeliveriesForChannel :many
+SELECT * FROM webhook_deliveries
+WHERE channel_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT 100;
+
+-- name: GetNotificationChannelForUser :one
+SELECT * FROM notification_channels
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreatePagingDelivery :exec
+INSERT INTO paging_deliveries (channel_id, service_id, dedup_key, action, status_code, error, success)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetPagingDeliveriesForChannel :many
+SELECT * FROM paging_deliveries
+WHERE channel_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT 100;
+
+-- name: EnqueueNotification :exec
+INSERT INTO notification_outbox (service_id, channel_id, channel_type, target, alert, max_attempts)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ClaimOutboxNotifications :many
+-- Leases a batch of due notifications to this dispatcher. SKIP LOCKED lets
+-- several replicas claim disjoint batches concurrently. Pages about a service
+-- wait until the earlier ones to the same channel are delivered or
+-- dead-lettered, so that a page is never triggered after it was resolved.
+UPDATE notification_outbox
+SET locked_until = now() + sqlc.arg(lease_seconds)::int * interval '1 second',
+    locked_by = sqlc.arg(instance_id)::text
+WHERE id IN (
+  SELECT o.id FROM notification_outbox o
+  WHERE o.status = 'pending'
+    AND o.next_attempt_at <= now()
+    AND (o.locked_until IS NULL OR o.locked_until < now())
+    AND NOT (o.channel_type IN ('pagerduty', 'opsgenie') AND EXISTS (
+      SELECT 1 FROM notification_outbox earlier
+      WHERE earlier.channel_id = o.channel_id
+        AND earlier.service_id = o.service_id
+        AND earlier.status = 'pending'
+        AND earlier.id < o.id
+    ))
+  ORDER BY o.next_attempt_at
+  LIMIT sqlc.arg(batch_size)::int
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkNotificationDelivered :exec
+UPDATE notification_outbox
+SET status = 'delivered', attempts = attempts + 1, delivered_at = now(),
+    last_error = NULL, locked_until = NULL, locked_by = NULL
+WHERE id = $1;
+
+-- name: MarkNotificationFailed :exec
+-- Schedules another attempt, or dead-letters the notification once it has
+-- used all its attempts.
+UPDATE notification_outbox
+SET attempts = attempts + 1,
+    status = CASE WHEN attempts + 1 >= max_attempts THEN 'failed' ELSE 'pending' END,
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    last_error = sqlc.arg(last_error),
+    locked_until = NULL, locked_by = NULL
+WHERE id = sqlc.arg(id);
+
+-- name: GetNotificationChannel :one
+SELECT * FROM notification_channels
+WHERE id = $1;
+
+-- name: GetFailedNotificationsForUser :many
+SELECT o.id, o.service_id, s.name as service_name, o.channel_id, o.channel_type,
+       o.alert, o.attempts, o.last_error, o.created_at, o.next_attempt_at
+FROM notification_outbox o
+JOIN services s ON o.service_id = s.id
+WHERE s.user_id = $1 AND o.status = 'failed'
+ORDER BY o.created_at DESC
+LIMIT 100;
+
+-- name: RetryFailedNotification :execrows
+UPDATE notification_outbox o
+SET status = 'pending', attempts = 0, next_attempt_at = now()
+FROM services s
+WHERE o.id = $1 AND o.service_id = s.id AND s.user_id = $2 AND o.status = 'failed';
+
+-- name: GetUptimeForService :one
+-- Checks during maintenance windows count neither as up nor as down.
+SELECT
+  count(*) FILTER (WHERE status <> 'maintenance') AS checks,
+  count(*) FILTER (WHERE status = 'down') AS down_checks,
+  count(*) FILTER (WHERE status = 'maintenance') AS maintenance_checks
+FROM status_checks
+WHERE service_id = $1 AND checked_at > $2;
+
+-- name: CreateMaintenanceWindow :one
+INSERT INTO maintenance_windows (user_id, title, description, starts_at, duration_seconds, recurrence, timezone, recurrence_until)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: AddMaintenanceWindowService :exec
+INSERT INTO maintenance_window_services (window_id, service_id)
+VALUES ($1, $2);
+
+-- name: GetMaintenanceWindowsForUser :many
+SELECT mw.*, array_agg(mws.service_id ORDER BY mws.service_id)::bigint[] AS service_ids
+FROM maintenance_windows mw
+JOIN maintenance_window_services mws ON mws.window_id = mw.id
+WHERE mw.user_id = $1
+GROUP BY mw.id
+ORDER BY mw.starts_at DESC;
+
+-- name: CancelMaintenanceWindow :execrows
+UPDATE maintenance_windows
+SET cancelled_at = now()
+WHERE id = $1 AND user_id = $2 AND cancelled_at IS NULL;
+
+-- name: GetMaintenanceWindowsForService :many
+-- Windows of the service that may be in progress at the given time; whether a
+-- recurring window has an occurrence in progress is decided by the caller.
+SELECT mw.*
+FROM maintenance_windows mw
+JOIN maintenance_window_services mws ON mws.window_id = mw.id
+WHERE mws.service_id = sqlc.arg(service_id)
+  AND mw.cancelled_at IS NULL
+  AND mw.starts_at <= sqlc.arg(at)::timestamptz
+  AND (
+    (mw.recurrence IS NULL AND mw.starts_at + mw.duration_seconds * interval '1 second' > sqlc.arg(at)::timestamptz)
+    OR (mw.recurrence IS NOT NULL AND (mw.recurrence_until IS NULL
+      OR mw.recurrence_until + mw.duration_seconds * interval '1 second' > sqlc.arg(at)::timestamptz))
+  );
+
+-- name: LockServiceDependencies :exec
+-- Serializes dependency changes of a user so that concurrent changes cannot
+-- create a cycle together. Released at the end of the transaction.
+SELECT pg_advisory_xact_lock(sqlc.arg(user_id)::bigint);
+
+-- name: GetDependencyEdgesForUser :many
+SELECT d.service_id, d.depends_on_id
+FROM service_dependencies d
+JOIN services s ON s.id = d.service_id
+WHERE s.user_id = $1;
+
+-- name: GetServiceDependencies :many
+SELECT depends_on_id FROM service_dependencies
+WHERE service_id = $1
+ORDER BY depends_on_id;
+
+-- name: DeleteServiceDependencies :exec
+DELETE FROM service_dependencies
+WHERE service_id = $1;
+
+-- name: AddServiceDependency :exec
+INSERT INTO service_dependencies (service_id, depends_on_id)
+VALUES ($1, $2);
+
+-- name: GetDownDependencies :many
+-- Services the service depends on that are down or unreachable, or whose
+-- latest check failed before the outage was confirmed.
+SELECT p.id, p.name
+FROM service_dependencies d
+JOIN services p ON p.id = d.depends_on_id
+WHERE d.service_id = $1
+  AND (
+    p.status IN ('down', 'unreachable')
+    OR (
+      SELECT sc.status FROM status_checks sc
+      WHERE sc.service_id = p.id AND sc.status <> 'maintenance'
+      ORDER BY sc.checked_at DESC
+      LIMIT 1
+    ) = 'down'
+  )
+ORDER BY p.name;
+
+-- name: GetDependentServiceNames :many
+-- Names of the services that depend on the service, directly or not.
+WITH RECURSIVE dependents AS (
+  SELECT d.service_id FROM service_dependencies d WHERE d.depends_on_id = $1
+  UNION
+  SELECT d.service_id FROM service_dependencies d
+  JOIN dependents ON d.depends_on_id = dependents.service_id
+)
+SELECT s.name
+FROM services s
+JOIN dependents ON dependents.service_id = s.id
+ORDER BY s.name;
+
+-- name: CreateEscalationPolicy :one
+INSERT INTO escalation_policies (user_id, name)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: CreateEscalationLevel :one
+INSERT INTO escalation_levels (policy_id, position, delay_minutes, channel_ids, emails)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetEscalationPoliciesForUser :many
+SELECT * FROM escalation_policies
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetEscalationLevelsForUser :many
+SELECT l.*
+FROM escalation_levels l
+JOIN escalation_policies p ON p.id = l.policy_id
+WHERE p.user_id = $1
+ORDER BY l.policy_id, l.position;
+
+-- name: GetEscalationPolicyForUser :one
+SELECT * FROM escalation_policies
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteEscalationPolicy :execrows
+DELETE FROM escalation_policies
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetEscalationLevels :many
+SELECT * FROM escalation_levels
+WHERE policy_id = $1
+ORDER BY position;
+
+-- name: SetServiceEscalationPolicy :execrows
+UPDATE services
+SET escalation_policy_id = sqlc.narg(escalation_policy_id)
+WHERE id = sqlc.arg(id) AND user_id = sqlc.arg(user_id);
+
+-- name: GetServiceAndOwner :one
+SELECT s.*, u.email as owner_email
+FROM services s
+JOIN users u ON s.user_id = u.id
+WHERE s.id = $1;
+
+-- name: ClaimDueEscalations :many
+-- Locks the open incidents due for their next escalation level until the end
+-- of the transaction; other instances skip them.
+SELECT * FROM incidents
+WHERE status = 'open' AND next_escalation_at <= now()
+ORDER BY next_escalation_at
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: UpdateIncidentEscalation :exec
+UPDATE incidents
+SET escalation_level = $2, next_escalation_at = $3
+WHERE id = $1;
+
+-- name: GetNotificationChannelsByIDs :many
+SELECT * FROM notification_channels
+WHERE user_id = sqlc.arg(user_id) AND id = ANY(sqlc.arg(ids)::bigint[]) AND enabled
+ORDER BY id;
+
+-- name: AcknowledgeIncidentByLink :one
+-- Acknowledges an incident from a signed link in an alert email, which is
+-- not tied to a user account.
+UPDATE incidents
+SET status = 'acknowledged', acknowledged_at = now()
+WHERE id = $1 AND status = 'open'
+RETURNING *;
+
+-- name: CreateOncallSchedule :one
+INSERT INTO oncall_schedules (user_id, name, rotation, members, timezone, starts_on, handoff_time)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetOncallSchedulesForUser :many
+SELECT * FROM oncall_schedules
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetOncallScheduleForUser :one
+SELECT * FROM oncall_schedules
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetOncallSchedule :one
+SELECT sc.*, u.email AS owner_email
+FROM oncall_schedules sc
+JOIN users u ON sc.user_id = u.id
+WHERE sc.id = $1;
+
+-- name: DeleteOncallSchedule :execrows
+DELETE FROM oncall_schedules
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateOncallOverride :one
+INSERT INTO oncall_overrides (schedule_id, email, starts_at, ends_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetOncallOverrides :many
+-- Overrides overlapping [range_start, range_end), oldest first so that later
+-- ones take precedence.
+SELECT * FROM oncall_overrides
+WHERE schedule_id = sqlc.arg(schedule_id)
+  AND ends_at > sqlc.arg(range_start)::timestamptz
+  AND starts_at < sqlc.arg(range_end)::timestamptz
+ORDER BY created_at, id;
+
+-- name: DeleteOncallOverride :execrows
+DELETE FROM oncall_overrides
+WHERE id = $1 AND schedule_id = $2;
+
+-- name: SetServiceOncallSchedule :execrows
+UPDATE services
+SET oncall_schedule_id = sqlc.narg(oncall_schedule_id)
+WHERE id = sqlc.arg(id) AND user_id = sqlc.arg(user_id);
+
+-- name: CreateStatusPage :one
+INSERT INTO status_pages (user_id, slug, custom_domain, title, logo_url, description)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: CreateStatusPageGroup :one
+INSERT INTO status_page_groups (page_id, name, position)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: CreateStatusPageComponent :one
+INSERT INTO status_page_components (group_id, service_id, name, position)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetStatusPagesForUser :many
+SELECT * FROM status_pages
+WHERE user_id = $1
+ORDER BY title;
+
+-- name: DeleteStatusPage :execrows
+DELETE FROM status_pages
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetStatusPageBySlug :one
+SELECT * FROM status_pages
+WHERE slug = $1;
+
+-- name: GetStatusPageByDomain :one
+SELECT * FROM status_pages
+WHERE custom_domain = $1;
+
+-- name: GetStatusPageComponents :many
+SELECT c.id, c.group_id, g.name AS group_name, c.service_id,
+  COALESCE(c.name, s.name)::text AS name, s.status
+FROM status_page_components c
+JOIN status_page_groups g ON g.id = c.group_id
+JOIN services s ON s.id = c.service_id
+WHERE g.page_id = $1
+ORDER BY g.position, c.position;
+
+-- name: GetDailyUptimeForServices :many
+-- Seconds spent in each confirmed status per UTC day, for the uptime bars of
+-- status pages. Time under maintenance counts neither as up nor as down.
+WITH periods AS (
+  SELECT service_id, status, changed_at AS started_at,
+    lead(changed_at, 1, now()) OVER (PARTITION BY service_id ORDER BY changed_at, id) AS ended_at
+  FROM status_changes
+  WHERE service_id = ANY(sqlc.arg(service_ids)::bigint[])
+), days AS (
+  SELECT p.service_id, p.status, d.day,
+    least(p.ended_at, (d.day + interval '1 day') AT TIME ZONE 'UTC')
+      - greatest(p.started_at, d.day AT TIME ZONE 'UTC', sqlc.arg(since)::timestamptz) AS duration
+  FROM periods p
+  CROSS JOIN generate_series(
+    date_trunc('day', greatest(p.started_at, sqlc.arg(since)::timestamptz) AT TIME ZONE 'UTC'),
+    p.ended_at AT TIME ZONE 'UTC',
+    interval '1 day'
+  ) AS d(day)
+  WHERE p.ended_at > sqlc.arg(since)::timestamptz
+)
+SELECT service_id,
+  day::date AS day,
+  COALESCE(extract(epoch FROM sum(duration) FILTER (WHERE status = 'up')), 0)::bigint AS up_seconds,
+  COALESCE(extract(epoch FROM sum(duration) FILTER (WHERE status = 'degraded')), 0)::bigint AS degraded_seconds,
+  COALESCE(extract(epoch FROM sum(duration) FILTER (WHERE status IN ('down', 'unreachable'))), 0)::bigint AS down_seconds
+FROM days
+GROUP BY service_id, day
+ORDER BY service_id, day;
+
+-- name: GetActiveIncidentsForServices :many
+SELECT * FROM incidents
+WHERE service_id = ANY(sqlc.arg(service_ids)::bigint[]) AND status <> 'resolved'
+ORDER BY started_at DESC;
+
+-- name: GetLatestIncidentUpdates :many
+-- The latest update on each of the incidents that has one.
+SELECT DISTINCT ON (incident_id) *
+FROM incident_updates
+WHERE incident_id = ANY(sqlc.arg(incident_ids)::bigint[])
+ORDER BY incident_id, created_at DESC, id DESC;
+
+-- name: CreateStatusPageSubscriber :one
+-- Returns no rows if the page already has this subscriber.
+INSERT INTO status_page_subscribers (page_id, type, target, component_ids, token, secret)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (page_id, type, target) DO NOTHING
+RETURNING *;
+
+-- name: GetStatusPageSubscriberByTarget :one
+SELECT * FROM status_page_subscribers
+WHERE page_id = $1 AND type = $2 AND target = $3;
+
+-- name: GetStatusPageSubscriber :one
+SELECT sub.*, p.slug AS page_slug
+FROM status_page_subscribers sub
+JOIN status_pages p ON p.id = sub.page_id
+WHERE sub.id = $1;
+
+-- name: GetStatusPageSubscriberByToken :one
+SELECT * FROM status_page_subscribers
+WHERE page_id = $1 AND token = $2;
+
+-- name: ConfirmStatusPageSubscriber :one
+UPDATE status_page_subscribers
+SET confirmed_at = COALESCE(confirmed_at, now())
+WHERE page_id = $1 AND token = $2
+RETURNING *;
+
+-- name: DeleteStatusPageSubscriberByToken :execrows
+DELETE FROM status_page_subscribers
+WHERE page_id = $1 AND token = $2;
+
+-- name: GetStatusPageSubscribers :many
+-- Subscribers of a page without their tokens and secrets.
+SELECT sub.id, sub.type, sub.target, sub.component_ids, sub.confirmed_at, sub.created_at
+FROM status_page_subscribers sub
+JOIN status_pages p ON p.id = sub.page_id
+WHERE sub.page_id = $1 AND p.user_id = $2
+ORDER BY sub.created_at;
+
+-- name: GetStatusPageSubscribersForService :many
+-- Confirmed subscribers of the pages showing the service, with the names of
+-- the service's components they are subscribed to.
+SELECT sub.id, p.title AS page_title,
+  array_agg(COALESCE(c.name, s.name) ORDER BY g.position, c.position)::text[] AS component_names
+FROM status_page_subscribers sub
+JOIN status_pages p ON p.id = sub.page_id
+JOIN status_page_groups g ON g.page_id = sub.page_id
+JOIN status_page_components c ON c.group_id = g.id
+JOIN services s ON s.id = c.service_id
+WHERE c.service_id = $1
+  AND sub.confirmed_at IS NOT NULL
+  AND (cardinality(sub.component_ids) = 0 OR c.id = ANY(sub.component_ids))
+GROUP BY sub.id, p.title;
+
+-- name: CreateIncidentUpdate :one
+INSERT INTO incident_updates (incident_id, status, message, user_id)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetIncidentUpdates :many
+SELECT * FROM incident_updates
+WHERE incident_id = $1
+ORDER BY created_at, id;
+
+-- name: GetEmailTemplates :many
+SELECT * FROM email_templates
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetEmailTemplatesForService :many
+-- The email templates overridden by the owner of the service.
+SELECT t.* FROM email_templates t
+JOIN services s ON s.user_id = t.user_id
+WHERE s.id = $1;
+
+-- name: UpsertEmailTemplate :one
+INSERT INTO email_templates (user_id, name, content)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, name) DO UPDATE SET content = EXCLUDED.content, updated_at = now()
+RETURNING *;
+
+-- name: DeleteEmailTemplate :execrows
+DELETE FROM email_templates
+WHERE user_id = $1 AND name = $2;
@@ -36,3 +36,17 @@ func (u *User) CheckPassword(plaintextPassword string) (bool, error) {
 	}
 	return true, nil
 }
+
+// HashPassword returns the bcrypt hash of a plaintext password.
+func HashPassword(plaintextPassword string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// CheckPasswordHash reports whether the plaintext password matches the stored hash.
+func CheckPasswordHash(plaintextPassword, hash string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(plaintextPassword)) == nil
+}
@@ -0,0 +1,358 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxAssertedBodyBytes caps how much of a response body is read for body
+// assertions when the service does not set max_response_bytes.
+const maxAssertedBodyBytes = 1 << 20
+
+// Assertions are the per-service rules that decide whether an HTTP response
+// is healthy. They are stored as JSON in services.assertions.
+type Assertions struct {
+	StatusCodes      []string            `json:"status_codes,omitempty"`      // "200", "200-299"; 2xx when empty
+	BodyContains     []string            `json:"body_contains,omitempty"`     // Keywords the body must contain
+	BodyNotContains  []string            `json:"body_not_contains,omitempty"` // Keywords the body must not contain
+	BodyMatches      []string            `json:"body_matches,omitempty"`      // Regular expressions the body must match
+	BodyNotMatches   []string            `json:"body_not_matches,omitempty"`  // Regular expressions the body must not match
+	JSONPath         []JSONPathAssertion `json:"json_path,omitempty"`
+	Headers          []HeaderAssertion   `json:"headers,omitempty"`
+	MaxResponseBytes int64               `json:"max_response_bytes,omitempty"`
+
+	// The rules compiled by Validate
+	statusRanges   [][2]int
+	bodyMatches    []*regexp.Regexp
+	bodyNotMatches []*regexp.Regexp
+	jsonPaths      [][]jsonPathStep
+	headerMatches  []*regexp.Regexp // By header rule, nil without Matches
+}
+
+// JSONPathAssertion requires the value at Path (e.g. "$.status") to equal Equals.
+type JSONPathAssertion struct {
+	Path   string          `json:"path"`
+	Equals json.RawMessage `json:"equals"`
+}
+
+// HeaderAssertion checks a response header. Only the non-empty conditions are
+// applied, and each one that does not hold is reported.
+type HeaderAssertion struct {
+	Name     string `json:"name"`
+	Equals   string `json:"equals,omitempty"`
+	Contains string `json:"contains,omitempty"`
+	Matches  string `json:"matches,omitempty"`
+}
+
+// ParseAssertions decodes, validates and compiles the assertions stored for
+// a service.
+func ParseAssertions(raw []byte) (*Assertions, error) {
+	a := &Assertions{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, a); err != nil {
+			return nil, fmt.Errorf("invalid assertions: %w", err)
+		}
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// cachedAssertions are the compiled assertions of a service, with the JSON
+// they were compiled from.
+type cachedAssertions struct {
+	raw        string
+	assertions *Assertions
+}
+
+// assertionsByService caches compiled assertions by service ID, so that they
+// are compiled again only when the service's assertions change.
+var assertionsByService sync.Map
+
+func loadAssertions(serviceID int64, raw []byte) (*Assertions, error) {
+	if cached, ok := assertionsByService.Load(serviceID); ok && cached.(cachedAssertions).raw == string(raw) {
+		return cached.(cachedAssertions).assertions, nil
+	}
+	a, err := ParseAssertions(raw)
+	if err != nil {
+		return nil, err
+	}
+	assertionsByService.Store(serviceID, cachedAssertions{raw: string(raw), assertions: a})
+	return a, nil
+}
+
+// Validate checks that every rule can be evaluated and compiles the rules
+// for the checks.
+func (a *Assertions) Validate() error {
+	a.statusRanges = a.statusRanges[:0]
+	for _, codes := range a.StatusCodes {
+		low, high, err := parseStatusRange(codes)
+		if err != nil {
+			return err
+		}
+		a.statusRanges = append(a.statusRanges, [2]int{low, high})
+	}
+
+	var err error
+	if a.bodyMatches, err = compilePatterns(a.BodyMatches); err != nil {
+		return err
+	}
+	if a.bodyNotMatches, err = compilePatterns(a.BodyNotMatches); err != nil {
+		return err
+	}
+
+	a.jsonPaths = a.jsonPaths[:0]
+	for _, jp := range a.JSONPath {
+		steps, err := parseJSONPath(jp.Path)
+		if err != nil {
+			return err
+		}
+		if !json.Valid(jp.Equals) {
+			return fmt.Errorf("invalid expected value for %s", jp.Path)
+		}
+		a.jsonPaths = append(a.jsonPaths, steps)
+	}
+
+	a.headerMatches = a.headerMatches[:0]
+	for _, h := range a.Headers {
+		if h.Name == "" {
+			return fmt.Errorf("header assertion requires a name")
+		}
+		var pattern *regexp.Regexp
+		if h.Matches != "" {
+			if pattern, err = regexp.Compile(h.Matches); err != nil {
+				return fmt.Errorf("invalid header regex %q: %w", h.Matches, err)
+			}
+		}
+		a.headerMatches = append(a.headerMatches, pattern)
+	}
+
+	if a.MaxResponseBytes < 0 {
+		return fmt.Errorf("max_response_bytes must not be negative")
+	}
+	return nil
+}
+
+// compilePatterns compiles the body regular expressions.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body regex %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// needsBody reports whether any rule inspects the response body.
+func (a *Assertions) needsBody() bool {
+	return len(a.BodyContains) > 0 || len(a.BodyNotContains) > 0 ||
+		len(a.BodyMatches) > 0 || len(a.BodyNotMatches) > 0 ||
+		len(a.JSONPath) > 0 || a.MaxResponseBytes > 0
+}
+
+// bodyLimit returns how many bytes of the body should be read.
+func (a *Assertions) bodyLimit() int64 {
+	if a.MaxResponseBytes > 0 {
+		return a.MaxResponseBytes
+	}
+	return maxAssertedBodyBytes
+}
+
+// checkStatus returns a failure message if the status code is not accepted.
+func (a *Assertions) checkStatus(code int) string {
+	if len(a.StatusCodes) == 0 {
+		if code >= 200 && code < 300 {
+			return ""
+		}
+		return fmt.Sprintf("Non-2xx status code: %d", code)
+	}
+
+	for _, codes := range a.statusRanges {
+		if code >= codes[0] && code <= codes[1] {
+			return ""
+		}
+	}
+	return fmt.Sprintf("status code %d not in accepted codes [%s]", code, strings.Join(a.StatusCodes, ", "))
+}
+
+// checkHeaders returns a failure message for each header rule that does not hold.
+func (a *Assertions) checkHeaders(header http.Header) []string {
+	var failures []string
+	for i, h := range a.Headers {
+		values, present := header[http.CanonicalHeaderKey(h.Name)]
+		if !present {
+			failures = append(failures, fmt.Sprintf("header %s is missing", h.Name))
+			continue
+		}
+		value := strings.Join(values, ", ")
+		if h.Equals != "" && value != h.Equals {
+			failures = append(failures, fmt.Sprintf("header %s is %q, expected %q", h.Name, value, h.Equals))
+		}
+		if h.Contains != "" && !strings.Contains(value, h.Contains) {
+			failures = append(failures, fmt.Sprintf("header %s does not contain %q", h.Name, h.Contains))
+		}
+		if pattern := a.headerMatches[i]; pattern != nil && !pattern.MatchString(value) {
+			failures = append(failures, fmt.Sprintf("header %s does not match %q", h.Name, h.Matches))
+		}
+	}
+	return failures
+}
+
+// checkBody returns a failure message for each body rule that does not hold.
+func (a *Assertions) checkBody(body []byte) []string {
+	var failures []string
+	text := string(body)
+
+	for _, keyword := range a.BodyContains {
+		if !strings.Contains(text, keyword) {
+			failures = append(failures, fmt.Sprintf("body does not contain %q", keyword))
+		}
+	}
+	for _, keyword := range a.BodyNotContains {
+		if strings.Contains(text, keyword) {
+			failures = append(failures, fmt.Sprintf("body contains %q", keyword))
+		}
+	}
+	for _, pattern := range a.bodyMatches {
+		if !pattern.MatchString(text) {
+			failures = append(failures, fmt.Sprintf("body does not match %q", pattern))
+		}
+	}
+	for _, pattern := range a.bodyNotMatches {
+		if pattern.MatchString(text) {
+			failures = append(failures, fmt.Sprintf("body matches %q", pattern))
+		}
+	}
+
+	if len(a.JSONPath) > 0 {
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return append(failures, fmt.Sprintf("body is not valid JSON: %v", err))
+		}
+		for i, jp := range a.JSONPath {
+			if msg := checkJSONPath(doc, jp, a.jsonPaths[i]); msg != "" {
+				failures = append(failures, msg)
+			}
+		}
+	}
+
+	return failures
+}
+
+// checkJSONPath returns a failure message if the value at the path differs
+// from the expected value.
+func checkJSONPath(doc interface{}, jp JSONPathAssertion, steps []jsonPathStep) string {
+	got, ok := evalJSONPath(doc, steps)
+	if !ok {
+		return fmt.Sprintf("%s not found in body", jp.Path)
+	}
+
+	var expected interface{}
+	json.Unmarshal(jp.Equals, &expected)
+	if !reflect.DeepEqual(got, expected) {
+		actual, _ := json.Marshal(got)
+		return fmt.Sprintf("%s is %s, expected %s", jp.Path, actual, jp.Equals)
+	}
+	return ""
+}
+
+// parseStatusRange parses "200" or "200-299" into an inclusive range.
+func parseStatusRange(codes string) (int, int, error) {
+	lowText, highText, isRange := strings.Cut(strings.TrimSpace(codes), "-")
+	low, err := strconv.Atoi(strings.TrimSpace(lowText))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid status code %q", codes)
+	}
+	high := low
+	if isRange {
+		if high, err = strconv.Atoi(strings.TrimSpace(highText)); err != nil || high < low {
+			return 0, 0, fmt.Errorf("invalid status code range %q", codes)
+		}
+	}
+	return low, high, nil
+}
+
+// jsonPathStep is either an object key or an array index.
+type jsonPathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseJSONPath parses the subset of JSONPath used for assertions:
+// $.a.b, $['a'], $.items[0].
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid JSON path %q: must start with $", path)
+	}
+
+	var steps []jsonPathStep
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end == -1 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("invalid JSON path %q: empty key", path)
+			}
+			steps = append(steps, jsonPathStep{key: key})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid JSON path %q: missing ]", path)
+			}
+			inner := rest[1:end]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				steps = append(steps, jsonPathStep{key: inner[1 : len(inner)-1]})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid JSON path %q: bad index %q", path, inner)
+				}
+				steps = append(steps, jsonPathStep{index: index, isIndex: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSON path %q", path)
+		}
+	}
+	return steps, nil
+}
+
+// evalJSONPath walks a decoded JSON document.
+func evalJSONPath(doc interface{}, steps []jsonPathStep) (interface{}, bool) {
+	current := doc
+	for _, step := range steps {
+		if step.isIndex {
+			list, ok := current.([]interface{})
+			if !ok || step.index >= len(list) {
+				return nil, false
+			}
+			current = list[step.index]
+			continue
+		}
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[step.key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
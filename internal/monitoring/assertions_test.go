@@ -0,0 +1,141 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseStatusRange(t *testing.T) {
+	tests := []struct {
+		codes     string
+		low, high int
+		wantErr   bool
+	}{
+		{"200", 200, 200, false},
+		{" 204 ", 204, 204, false},
+		{"200-299", 200, 299, false},
+		{"300 - 399", 300, 399, false},
+		{"500-500", 500, 500, false},
+		{"299-200", 0, 0, true},
+		{"2xx", 0, 0, true},
+		{"200-", 0, 0, true},
+		{"", 0, 0, true},
+	}
+	for _, tt := range tests {
+		low, high, err := parseStatusRange(tt.codes)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseStatusRange(%q) error = %v, want error %v", tt.codes, err, tt.wantErr)
+			continue
+		}
+		if low != tt.low || high != tt.high {
+			t.Errorf("parseStatusRange(%q) = %d, %d, want %d, %d", tt.codes, low, high, tt.low, tt.high)
+		}
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []jsonPathStep
+		wantErr bool
+	}{
+		{"$", nil, false},
+		{"$.status", []jsonPathStep{{key: "status"}}, false},
+		{"$.data.status", []jsonPathStep{{key: "data"}, {key: "status"}}, false},
+		{"$['a.b']", []jsonPathStep{{key: "a.b"}}, false},
+		{`$["a"]`, []jsonPathStep{{key: "a"}}, false},
+		{"$.items[0].name", []jsonPathStep{{key: "items"}, {index: 0, isIndex: true}, {key: "name"}}, false},
+		{"$[2][1]", []jsonPathStep{{index: 2, isIndex: true}, {index: 1, isIndex: true}}, false},
+		{"status", nil, true},
+		{"$.", nil, true},
+		{"$..a", nil, true},
+		{"$.items[0", nil, true},
+		{"$.items[-1]", nil, true},
+		{"$.items[x]", nil, true},
+		{"$status", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseJSONPath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseJSONPath(%q) error = %v, want error %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseJSONPath(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestEvalJSONPath(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{"status":"ok","items":[{"name":"a"},{"name":"b"}],"a.b":1,"nested":{"n":null}}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path   string
+		want   interface{}
+		wantOK bool
+	}{
+		{"$.status", "ok", true},
+		{"$.items[1].name", "b", true},
+		{"$['a.b']", float64(1), true},
+		{"$.nested.n", nil, true},
+		{"$.items[2]", nil, false},
+		{"$.missing", nil, false},
+		{"$.status.length", nil, false},
+		{"$.items.name", nil, false},
+		{"$.status[0]", nil, false},
+	}
+	for _, tt := range tests {
+		steps, err := parseJSONPath(tt.path)
+		if err != nil {
+			t.Fatalf("parseJSONPath(%q) failed: %v", tt.path, err)
+		}
+		got, ok := evalJSONPath(doc, steps)
+		if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("evalJSONPath(%q) = %v, %v, want %v, %v", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCheckHeaders(t *testing.T) {
+	a, err := ParseAssertions([]byte(`{"headers":[
+		{"name":"content-type","equals":"text/html","contains":"json","matches":"^application/"},
+		{"name":"X-Missing","equals":"1"},
+		{"name":"Cache-Control","contains":"no-store"}
+	]}`))
+	if err != nil {
+		t.Fatalf("ParseAssertions failed: %v", err)
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain")
+	header.Set("Cache-Control", "private, no-store")
+
+	want := []string{
+		`header content-type is "text/plain", expected "text/html"`,
+		`header content-type does not contain "json"`,
+		`header content-type does not match "^application/"`,
+		"header X-Missing is missing",
+	}
+	if got := a.checkHeaders(header); !reflect.DeepEqual(got, want) {
+		t.Errorf("checkHeaders = %q, want %q", got, want)
+	}
+}
+
+func TestParseAssertionsErrors(t *testing.T) {
+	for _, raw := range []string{
+		`{"status_codes":["2xx"]}`,
+		`{"body_matches":["("]}`,
+		`{"body_not_matches":["[a-"]}`,
+		`{"json_path":[{"path":"status","equals":"ok"}]}`,
+		`{"headers":[{"name":"X-Id","matches":"*"}]}`,
+		`{"headers":[{"equals":"1"}]}`,
+		`{"max_response_bytes":-1}`,
+	} {
+		if _, err := ParseAssertions([]byte(raw)); err == nil {
+			t.Errorf("ParseAssertions(%s) succeeded", raw)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package monitoring
+
+import (
+	"context"
+	"time"
+	"uptime-monitor/internal/database/db"
+)
+
+// Status values recorded in status_checks and services.
+const (
+	StatusUp       = "up"
+	StatusDegraded = "degraded" // Up, but slower than the service latency thresholds
+	StatusDown     = "down"
+
+	// Confirmed status of a failing service while a service it depends on is
+	// down. Checks themselves are never recorded as unreachable.
+	StatusUnreachable = "unreachable"
+
+	// Recorded instead of the outcome for checks run during a maintenance
+	// window. Such checks never change the service status.
+	StatusMaintenance = "maintenance"
+)
+
+// Service types. The type column on services selects which Checker is used.
+const (
+	TypeHTTP      = "http"
+	TypeTCP       = "tcp"
+	TypeDNS       = "dns"
+	TypeTLS       = "tls"
+	TypeHeartbeat = "heartbeat"
+)
+
+// defaultTimeout bounds a single check when the service does not set one.
+const defaultTimeout = 10 * time.Second
+
+// timeoutFor returns the per-check timeout configured for a service.
+func timeoutFor(s db.GetServicesAndOwnersRow) time.Duration {
+	if s.TimeoutSeconds > 0 {
+		return time.Duration(s.TimeoutSeconds) * time.Second
+	}
+	return defaultTimeout
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status       string
+	StatusCode   int           // Zero when the check has no status code (e.g. TCP)
+	ResponseTime time.Duration // Zero when no latency was measured
+	Err          error
+	Cert         *CertInfo // Set when the peer certificate was inspected
+	ExitCode     *int      // Set when a heartbeat job reported its exit code
+	Output       string    // Log output sent by a heartbeat job
+}
+
+// Checker performs a single check against a service target.
+type Checker interface {
+	Check(ctx context.Context, s db.GetServicesAndOwnersRow) Result
+}
+
+// defaultCheckers returns the checkers available for each service type.
+func defaultCheckers() map[string]Checker {
+	return map[string]Checker{
+		TypeHTTP:      &HTTPChecker{},
+		TypeTCP:       &TCPChecker{},
+		TypeDNS:       &DNSChecker{},
+		TypeTLS:       &TLSChecker{},
+		TypeHeartbeat: &HeartbeatChecker{},
+	}
+}
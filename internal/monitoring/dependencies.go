@@ -0,0 +1,35 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"uptime-monitor/internal/database/db"
+)
+
+// rootCause returns the names of the services s depends on that are down, or
+// nil if there are none. While any is down, s is unreachable rather than down.
+func rootCause(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow) ([]string, error) {
+	parents, err := q.GetDownDependencies(ctx, s.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get dependencies: %w", err)
+	}
+	var names []string
+	for _, parent := range parents {
+		names = append(names, parent.Name)
+	}
+	return names, nil
+}
+
+// affectedServices returns a sentence listing the services that depend on s
+// and are therefore affected by its outage, or "" if there are none.
+func affectedServices(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow) (string, error) {
+	names, err := q.GetDependentServiceNames(ctx, s.ID)
+	if err != nil {
+		return "", fmt.Errorf("could not get dependent services: %w", err)
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("Services depending on it are unreachable and are not notified separately: %s", strings.Join(names, ", ")), nil
+}
@@ -0,0 +1,144 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+	"uptime-monitor/internal/database/db"
+)
+
+// DNSChecker resolves a record for the target name and compares the answer
+// set with the expected values. NXDOMAIN, SERVFAIL and timeouts are reported
+// as down.
+type DNSChecker struct{}
+
+// Check implements Checker.
+func (c *DNSChecker) Check(ctx context.Context, s db.GetServicesAndOwnersRow) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeoutFor(s))
+	defer cancel()
+
+	recordType := "A"
+	if s.DnsRecordType.Valid && s.DnsRecordType.String != "" {
+		recordType = strings.ToUpper(s.DnsRecordType.String)
+	}
+
+	resolver := net.DefaultResolver
+	if s.DnsResolver.Valid && s.DnsResolver.String != "" {
+		resolver = newResolver(s.DnsResolver.String, timeoutFor(s))
+	}
+
+	startTime := time.Now()
+	answers, err := lookup(ctx, resolver, recordType, s.Target)
+	responseTime := time.Since(startTime)
+
+	if err != nil {
+		return Result{Status: StatusDown, ResponseTime: responseTime, Err: err}
+	}
+
+	result := Result{Status: StatusUp, ResponseTime: responseTime}
+	if len(answers) == 0 {
+		result.Status = StatusDown
+		result.Err = fmt.Errorf("no %s records for %s", recordType, s.Target)
+	} else if len(s.DnsExpected) > 0 && !sameAnswers(answers, s.DnsExpected) {
+		result.Status = StatusDown
+		result.Err = fmt.Errorf("unexpected %s answer for %s: got [%s], expected [%s]",
+			recordType, s.Target, strings.Join(answers, ", "), strings.Join(s.DnsExpected, ", "))
+	}
+
+	return result
+}
+
+// newResolver returns a resolver that sends every query to the given ip:port.
+func newResolver(address string, timeout time.Duration) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: timeout}
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// lookup resolves a single record type and returns the answers as strings.
+func lookup(ctx context.Context, r *net.Resolver, recordType, name string) ([]string, error) {
+	var answers []string
+
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case "MX":
+		mxs, err := r.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			answers = append(answers, mx.Host)
+		}
+	case "TXT":
+		txts, err := r.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, txts...)
+	case "NS":
+		nss, err := r.LookupNS(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nss {
+			answers = append(answers, ns.Host)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported record type %q", recordType)
+	}
+
+	return answers, nil
+}
+
+// sameAnswers reports whether both answer sets contain the same values,
+// ignoring order, case and trailing dots.
+func sameAnswers(got, expected []string) bool {
+	normalize := func(values []string) []string {
+		out := make([]string, 0, len(values))
+		seen := make(map[string]bool)
+		for _, v := range values {
+			v = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(v)), ".")
+			if !seen[v] {
+				seen[v] = true
+				out = append(out, v)
+			}
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	a, b := normalize(got), normalize(expected)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
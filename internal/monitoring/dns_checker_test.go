@@ -0,0 +1,233 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// stubRecords are the answers of the stub DNS server by query type and name.
+// Names not in the map are answered with NXDOMAIN.
+type stubRecords map[dnsmessage.Type]map[string][]dnsmessage.ResourceBody
+
+// startDNSStub serves the records over UDP on a local port until the test
+// ends, and returns its address.
+func startDNSStub(t *testing.T, records stubRecords) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			answer := stubAnswer(query, records)
+			resp, err := answer.Pack()
+			if err != nil {
+				t.Errorf("could not pack response: %v", err)
+				return
+			}
+			conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// stubAnswer builds the response to a single question.
+func stubAnswer(query dnsmessage.Message, records stubRecords) dnsmessage.Message {
+	q := query.Questions[0]
+	resp := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               query.ID,
+			Response:         true,
+			Authoritative:    true,
+			RecursionDesired: query.RecursionDesired,
+		},
+		Questions: query.Questions,
+	}
+
+	name := strings.ToLower(q.Name.String())
+	known := false
+	for _, byName := range records {
+		if _, ok := byName[name]; ok {
+			known = true
+		}
+	}
+	if !known {
+		resp.RCode = dnsmessage.RCodeNameError
+		return resp
+	}
+
+	for _, body := range records[q.Type][name] {
+		resp.Answers = append(resp.Answers, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60},
+			Body:   body,
+		})
+	}
+	return resp
+}
+
+func mustName(t *testing.T, name string) dnsmessage.Name {
+	t.Helper()
+	n, err := dnsmessage.NewName(name)
+	if err != nil {
+		t.Fatalf("invalid name %q: %v", name, err)
+	}
+	return n
+}
+
+func testRecords(t *testing.T) stubRecords {
+	return stubRecords{
+		dnsmessage.TypeA: {
+			"example.com.": {
+				&dnsmessage.AResource{A: netip.MustParseAddr("192.0.2.1").As4()},
+				&dnsmessage.AResource{A: netip.MustParseAddr("192.0.2.2").As4()},
+			},
+		},
+		dnsmessage.TypeAAAA: {
+			"example.com.": {
+				&dnsmessage.AAAAResource{AAAA: netip.MustParseAddr("2001:db8::1").As16()},
+			},
+		},
+		dnsmessage.TypeCNAME: {
+			"www.example.com.": {&dnsmessage.CNAMEResource{CNAME: mustName(t, "example.com.")}},
+		},
+		dnsmessage.TypeMX: {
+			"example.com.": {
+				&dnsmessage.MXResource{Pref: 20, MX: mustName(t, "mx2.example.com.")},
+				&dnsmessage.MXResource{Pref: 10, MX: mustName(t, "mx1.example.com.")},
+			},
+		},
+		dnsmessage.TypeTXT: {
+			"example.com.": {&dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}}},
+		},
+		dnsmessage.TypeNS: {
+			"example.com.": {
+				&dnsmessage.NSResource{NS: mustName(t, "ns1.example.com.")},
+				&dnsmessage.NSResource{NS: mustName(t, "ns2.example.com.")},
+			},
+		},
+	}
+}
+
+func TestLookup(t *testing.T) {
+	resolver := newResolver(startDNSStub(t, testRecords(t)), time.Second)
+
+	tests := []struct {
+		recordType string
+		name       string
+		want       []string
+	}{
+		{"A", "example.com.", []string{"192.0.2.1", "192.0.2.2"}},
+		{"AAAA", "example.com.", []string{"2001:db8::1"}},
+		{"CNAME", "www.example.com.", []string{"example.com."}},
+		{"MX", "example.com.", []string{"mx1.example.com.", "mx2.example.com."}},
+		{"TXT", "example.com.", []string{"v=spf1 -all"}},
+		{"NS", "example.com.", []string{"ns1.example.com.", "ns2.example.com."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.recordType, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			got, err := lookup(ctx, resolver, tt.recordType, tt.name)
+			if err != nil {
+				t.Fatalf("lookup(%s, %s) failed: %v", tt.recordType, tt.name, err)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("lookup(%s, %s) = %v, want %v", tt.recordType, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupNXDomain(t *testing.T) {
+	resolver := newResolver(startDNSStub(t, testRecords(t)), time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := lookup(ctx, resolver, "A", "missing.example.com.")
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		t.Errorf("lookup of a missing name: got error %v, want not found", err)
+	}
+}
+
+func TestLookupUnsupportedType(t *testing.T) {
+	if _, err := lookup(context.Background(), net.DefaultResolver, "SRV", "example.com."); err == nil {
+		t.Error("lookup of an unsupported record type succeeded")
+	}
+}
+
+func TestSameAnswers(t *testing.T) {
+	tests := []struct {
+		got, expected []string
+		want          bool
+	}{
+		{[]string{"192.0.2.1", "192.0.2.2"}, []string{"192.0.2.2", "192.0.2.1"}, true},
+		{[]string{"MX1.Example.com."}, []string{"mx1.example.com"}, true},
+		{[]string{"192.0.2.1", "192.0.2.1"}, []string{"192.0.2.1"}, true},
+		{[]string{" 192.0.2.1 "}, []string{"192.0.2.1"}, true},
+		{[]string{"192.0.2.1"}, []string{"192.0.2.1", "192.0.2.2"}, false},
+		{[]string{"192.0.2.1"}, []string{"192.0.2.3"}, false},
+	}
+	for _, tt := range tests {
+		if got := sameAnswers(tt.got, tt.expected); got != tt.want {
+			t.Errorf("sameAnswers(%q, %q) = %v, want %v", tt.got, tt.expected, got, tt.want)
+		}
+	}
+}
+
+func TestDNSCheckerCheck(t *testing.T) {
+	address := startDNSStub(t, testRecords(t))
+	service := func(target, recordType string, expected ...string) db.GetServicesAndOwnersRow {
+		return db.GetServicesAndOwnersRow{
+			Target:         target,
+			DnsRecordType:  pgtype.Text{String: recordType, Valid: true},
+			DnsResolver:    pgtype.Text{String: address, Valid: true},
+			DnsExpected:    expected,
+			TimeoutSeconds: 5,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		service db.GetServicesAndOwnersRow
+		want    string
+	}{
+		{"any answer", service("example.com.", "A"), StatusUp},
+		{"expected answers", service("example.com.", "A", "192.0.2.2", "192.0.2.1"), StatusUp},
+		{"unexpected answers", service("example.com.", "A", "192.0.2.1"), StatusDown},
+		{"expected CNAME", service("www.example.com.", "CNAME", "example.com"), StatusUp},
+		{"no records", service("www.example.com.", "TXT"), StatusDown},
+		{"missing name", service("missing.example.com.", "A"), StatusDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := (&DNSChecker{}).Check(context.Background(), tt.service)
+			if result.Status != tt.want {
+				t.Errorf("status = %s (%v), want %s", result.Status, result.Err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+	"uptime-monitor/internal/config"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/notifications"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// escalationBatchSize bounds the incidents escalated in one transaction.
+const escalationBatchSize = 50
+
+// Escalator notifies the next level of a service's escalation policy when an
+// incident is still open, i.e. not acknowledged or resolved, once the level's
+// delay has passed. Several instances can run at once: due incidents are
+// locked by the one escalating them.
+type Escalator struct {
+	pool *pgxpool.Pool
+	q    *db.Queries
+
+	notifyMaxAttempts int
+	pollInterval      time.Duration
+
+	stopped chan struct{}
+}
+
+// NewEscalator creates an escalator polling for due incidents at the
+// configured interval.
+func NewEscalator(cfg *config.Config, pool *pgxpool.Pool) *Escalator {
+	e := &Escalator{
+		pool:              pool,
+		q:                 db.New(pool),
+		notifyMaxAttempts: cfg.NotifyMaxAttempts,
+		pollInterval:      cfg.EscalationPollInterval,
+		stopped:           make(chan struct{}),
+	}
+	if e.pollInterval <= 0 {
+		e.pollInterval = 30 * time.Second
+	}
+	if e.notifyMaxAttempts <= 0 {
+		e.notifyMaxAttempts = 1
+	}
+	return e
+}
+
+// Start escalates due incidents until ctx is cancelled.
+func (e *Escalator) Start(ctx context.Context) {
+	defer close(e.stopped)
+	log.Println("Escalation worker started")
+
+	ticker := time.NewTicker(e.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Escalation worker stopped")
+			return
+		case <-ticker.C:
+		}
+
+		if err := e.escalateDue(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("ERROR: Could not escalate incidents: %v", err)
+		}
+	}
+}
+
+// Shutdown waits for the escalator to stop, or for ctx to expire.
+func (e *Escalator) Shutdown(ctx context.Context) error {
+	select {
+	case <-e.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// escalateDue notifies the due levels of a batch of incidents in one
+// transaction, so that each level is queued exactly once.
+func (e *Escalator) escalateDue(ctx context.Context) error {
+	tx, err := e.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // No-op once committed
+	q := e.q.WithTx(tx)
+
+	incidents, err := q.ClaimDueEscalations(ctx, escalationBatchSize)
+	if err != nil {
+		return fmt.Errorf("could not claim incidents: %w", err)
+	}
+	for _, incident := range incidents {
+		s, err := q.GetServiceAndOwner(ctx, incident.ServiceID)
+		if err != nil {
+			return fmt.Errorf("could not get service %d: %w", incident.ServiceID, err)
+		}
+		if err := escalateIncident(ctx, q, db.GetServicesAndOwnersRow(s), incident, nil, e.notifyMaxAttempts); err != nil {
+			return fmt.Errorf("could not escalate incident %d: %w", incident.ID, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// escalateIncident notifies every level of the service's escalation policy
+// whose delay has passed since the incident started and schedules the next
+// one. Levels are counted from the incident's escalation level, so each is
+// notified once. If initial is set, it is sent to the first level instead of
+// an escalation alert; it's the alert for the service going down.
+func escalateIncident(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow, incident db.Incident, initial *notifications.Alert, maxAttempts int) error {
+	var levels []db.EscalationLevel
+	if s.EscalationPolicyID.Valid {
+		var err error
+		if levels, err = q.GetEscalationLevels(ctx, s.EscalationPolicyID.Int64); err != nil {
+			return fmt.Errorf("could not get escalation levels: %w", err)
+		}
+	}
+
+	started := incident.StartedAt.Time
+	now := time.Now()
+	level := int(incident.EscalationLevel)
+	for ; level < len(levels); level++ {
+		if levelDue(started, levels[level]).After(now) {
+			break
+		}
+
+		var alert notifications.Alert
+		if level == 0 && initial != nil {
+			alert = *initial
+		} else {
+			alert = escalationAlert(s, incident, now.Sub(started))
+		}
+		alert.IncidentID = incident.ID
+		alert.EscalationLevel = level + 1
+
+		channels, err := levelChannels(ctx, q, s, levels[level])
+		if err != nil {
+			return err
+		}
+		log.Printf("ESCALATION for %s: notifying level %d of incident %d", s.Name, level+1, incident.ID)
+		if err := notifications.Enqueue(ctx, q, channels, fallbackChannel(s), alert, maxAttempts); err != nil {
+			return fmt.Errorf("could not queue notification: %w", err)
+		}
+
+		message := pgtype.Text{String: fmt.Sprintf("Escalated to level %d", level+1), Valid: true}
+		if err := addIncidentEvent(ctx, q, incident.ID, EventEscalated, message); err != nil {
+			return err
+		}
+	}
+
+	var next pgtype.Timestamptz
+	if level < len(levels) {
+		next = pgtype.Timestamptz{Time: levelDue(started, levels[level]), Valid: true}
+	}
+	return q.UpdateIncidentEscalation(ctx, db.UpdateIncidentEscalationParams{
+		ID:               incident.ID,
+		EscalationLevel:  int32(level),
+		NextEscalationAt: next,
+	})
+}
+
+// notifyEscalated sends the alert, usually the recovery, to the targets of
+// every level the incident was escalated to.
+func notifyEscalated(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow, incident db.Incident, alert notifications.Alert, maxAttempts int) error {
+	levels, err := q.GetEscalationLevels(ctx, s.EscalationPolicyID.Int64)
+	if err != nil {
+		return fmt.Errorf("could not get escalation levels: %w", err)
+	}
+
+	var channels []db.NotificationChannel
+	for i, level := range levels {
+		if i >= int(incident.EscalationLevel) {
+			break
+		}
+		targets, err := levelChannels(ctx, q, s, level)
+		if err != nil {
+			return err
+		}
+		channels = append(channels, targets...)
+	}
+	if err := notifications.Enqueue(ctx, q, dedupeChannels(channels), fallbackChannel(s), alert, maxAttempts); err != nil {
+		return fmt.Errorf("could not queue notification: %w", err)
+	}
+	return nil
+}
+
+// levelDue returns when the level is notified for an incident started then.
+func levelDue(started time.Time, level db.EscalationLevel) time.Time {
+	return started.Add(time.Duration(level.DelayMinutes) * time.Minute)
+}
+
+// levelChannels returns the enabled notification channels of the level, plus
+// one email channel per address. Channels deleted since the policy was saved
+// are skipped.
+func levelChannels(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow, level db.EscalationLevel) ([]db.NotificationChannel, error) {
+	var channels []db.NotificationChannel
+	if len(level.ChannelIds) > 0 {
+		var err error
+		channels, err = q.GetNotificationChannelsByIDs(ctx, db.GetNotificationChannelsByIDsParams{
+			UserID: s.UserID,
+			Ids:    level.ChannelIds,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not get notification channels: %w", err)
+		}
+	}
+	for _, email := range level.Emails {
+		channels = append(channels, db.NotificationChannel{Type: notifications.ChannelEmail, Target: email})
+	}
+	return channels, nil
+}
+
+// dedupeChannels drops channels listed by more than one level.
+func dedupeChannels(channels []db.NotificationChannel) []db.NotificationChannel {
+	seen := make(map[string]bool)
+	unique := channels[:0]
+	for _, channel := range channels {
+		key := channel.Type + ":" + channel.Target
+		if channel.ID != 0 {
+			key = fmt.Sprint(channel.ID)
+		}
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, channel)
+		}
+	}
+	return unique
+}
+
+// escalationAlert returns the alert sent to a level after the first.
+func escalationAlert(s db.GetServicesAndOwnersRow, incident db.Incident, open time.Duration) notifications.Alert {
+	subject := fmt.Sprintf("Escalation: %s is still DOWN", s.Name)
+	body := fmt.Sprintf("Incident #%d on your service '%s' (%s) has not been acknowledged for %s.\n\nStarted at: %s",
+		incident.ID, s.Name, alertTarget(s), open.Round(time.Minute), incident.StartedAt.Time.Format(time.RFC1123))
+	alert := newAlert(s, Result{}, notifications.EventIncidentEscalated, subject, body)
+	alert.Status = StatusDown
+	if incident.FirstError.Valid {
+		alert.Error = incident.FirstError.String
+	}
+	return alert
+}
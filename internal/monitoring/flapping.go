@@ -0,0 +1,106 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/notifications"
+)
+
+// StatusFlapping is the status reported in the alert sent when a service
+// starts flapping. The service keeps its confirmed status meanwhile.
+const StatusFlapping = "flapping"
+
+// flapRatio returns the share of consecutive checks, newest first, whose
+// statuses differ: 0 for a stable service and 1 when every check changed state.
+func flapRatio(statuses []string) float64 {
+	if len(statuses) < 2 {
+		return 0
+	}
+	changes := 0
+	for i := 1; i < len(statuses); i++ {
+		if statuses[i] != statuses[i-1] {
+			changes++
+		}
+	}
+	return float64(changes) / float64(len(statuses)-1)
+}
+
+// trackFlapping evaluates the state change ratio over the service's latest
+// central checks and reports whether the service is flapping. A service
+// starts flapping when the ratio reaches its threshold and stabilizes once it
+// falls below half of it, so that it does not toggle around the threshold.
+// The start and end of flapping are each announced once.
+func (m *Monitor) trackFlapping(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow, result Result) (bool, error) {
+	if s.FlapThreshold <= 0 {
+		// Detection was turned off while the service was flapping.
+		if s.Flapping {
+			_, err := m.setFlapping(ctx, q, s, false)
+			return false, err
+		}
+		return false, nil
+	}
+
+	statuses, err := q.GetRecentCheckStatuses(ctx, db.GetRecentCheckStatusesParams{
+		ServiceID: s.ID,
+		Location:  LocationCentral,
+		Limit:     s.FlapWindow,
+	})
+	if err != nil {
+		return s.Flapping, fmt.Errorf("could not get recent checks: %w", err)
+	}
+	// Not enough history yet to tell
+	if len(statuses) < int(s.FlapWindow) {
+		return s.Flapping, nil
+	}
+
+	ratio := flapRatio(statuses)
+	threshold := float64(s.FlapThreshold) / 100
+	switch {
+	case !s.Flapping && ratio >= threshold:
+		updated, err := m.setFlapping(ctx, q, s, true)
+		if err != nil || !updated {
+			return true, err
+		}
+		log.Printf("FLAPPING for %s: %.0f%% state changes in %d checks. Suppressing state change notifications.", s.Name, ratio*100, len(statuses))
+		subject := fmt.Sprintf("Uptime Alert: %s is FLAPPING", s.Name)
+		body := fmt.Sprintf("Your service '%s' (%s) changed state in %.0f%% of its last %d checks. Notifications for each change are paused until it stabilizes.\n\nChecked at: %s",
+			s.Name, alertTarget(s), ratio*100, len(statuses), time.Now().Format(time.RFC1123))
+		alert := newAlert(s, result, notifications.EventServiceFlapping, subject, body)
+		alert.Status = StatusFlapping
+		alert.PreviousStatus = s.Status.String
+		return true, m.notify(ctx, q, s, alert)
+
+	case s.Flapping && ratio < threshold/2:
+		updated, err := m.setFlapping(ctx, q, s, false)
+		if err != nil || !updated {
+			return false, err
+		}
+		status := s.Status.String
+		log.Printf("STABILIZED %s: %.0f%% state changes in %d checks, now %s. Resuming notifications.", s.Name, ratio*100, len(statuses), status)
+		subject := fmt.Sprintf("Uptime Alert: %s has stabilized and is %s", s.Name, strings.ToUpper(status))
+		body := fmt.Sprintf("Your service '%s' (%s) has stopped flapping and is now %s. Notifications for each change have resumed.\n\nChecked at: %s",
+			s.Name, alertTarget(s), status, time.Now().Format(time.RFC1123))
+		alert := newAlert(s, result, notifications.EventServiceStabilized, subject, body)
+		alert.Status = status
+		alert.PreviousStatus = StatusFlapping
+		return false, m.notify(ctx, q, s, alert)
+	}
+	return s.Flapping, nil
+}
+
+// setFlapping persists whether the service is flapping. It returns false if
+// the change was already recorded (e.g. by another replica).
+func (m *Monitor) setFlapping(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow, flapping bool) (bool, error) {
+	updated, err := q.SetServiceFlapping(ctx, db.SetServiceFlappingParams{
+		ID:       s.ID,
+		Flapping: flapping,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to update flapping state: %w", err)
+	}
+	return updated > 0, nil
+}
@@ -0,0 +1,69 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"uptime-monitor/internal/database/db"
+)
+
+// Heartbeat outcomes stored in services.last_heartbeat_status.
+const (
+	HeartbeatSuccess = "success"
+	HeartbeatFail    = "fail"
+)
+
+// HeartbeatChecker evaluates push-based services. Jobs ping
+// /heartbeat/:token, which only updates the service, and the service is down
+// if no ping arrived within the check interval plus the grace period, if a run
+// that pinged /start has not reported back within the grace period, or if the
+// last run reported a failure. The check following a run carries its exit
+// code, duration and log output.
+type HeartbeatChecker struct{}
+
+// Check implements Checker.
+func (c *HeartbeatChecker) Check(ctx context.Context, s db.GetServicesAndOwnersRow) Result {
+	last := s.CreatedAt.Time
+	if s.LastHeartbeatAt.Valid {
+		last = s.LastHeartbeatAt.Time
+	}
+
+	period := time.Duration(s.CheckIntervalSeconds) * time.Second
+	grace := time.Duration(s.HeartbeatGraceSeconds) * time.Second
+	if deadline := last.Add(period + grace); time.Now().After(deadline) {
+		if !s.LastHeartbeatAt.Valid {
+			return Result{Status: StatusDown, Err: fmt.Errorf("no heartbeat received yet")}
+		}
+		return Result{Status: StatusDown, Err: fmt.Errorf("no heartbeat received since %s", last.Format(time.RFC1123))}
+	}
+
+	if s.HeartbeatStartedAt.Valid {
+		started := s.HeartbeatStartedAt.Time
+		if running := time.Since(started); running > grace {
+			return Result{Status: StatusDown, Err: fmt.Errorf("run started at %s is still running after %s",
+				started.Format(time.RFC1123), running.Round(time.Second))}
+		}
+	}
+
+	result := Result{Status: StatusUp}
+	// Only the first check after a run reports it, not every check until the next
+	if s.LastHeartbeatAt.Valid && time.Since(last) < period {
+		if s.LastHeartbeatExitCode.Valid {
+			exitCode := int(s.LastHeartbeatExitCode.Int32)
+			result.ExitCode = &exitCode
+		}
+		if s.LastHeartbeatDurationMs.Valid {
+			result.ResponseTime = time.Duration(s.LastHeartbeatDurationMs.Int32) * time.Millisecond
+		}
+		result.Output = s.LastHeartbeatPayload.String
+	}
+
+	if s.LastHeartbeatStatus.String == HeartbeatFail {
+		result.Status = StatusDown
+		result.Err = fmt.Errorf("last run reported a failure at %s", last.Format(time.RFC1123))
+		if s.LastHeartbeatExitCode.Valid {
+			result.Err = fmt.Errorf("last run reported a failure (exit code %d) at %s", s.LastHeartbeatExitCode.Int32, last.Format(time.RFC1123))
+		}
+	}
+	return result
+}
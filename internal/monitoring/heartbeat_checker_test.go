@@ -0,0 +1,69 @@
+package monitoring
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/notifications"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestHeartbeatCheck(t *testing.T) {
+	ago := func(d time.Duration) pgtype.Timestamptz {
+		return pgtype.Timestamptz{Time: time.Now().Add(-d), Valid: true}
+	}
+	success := pgtype.Text{String: HeartbeatSuccess, Valid: true}
+	tests := []struct {
+		name       string
+		lastPing   pgtype.Timestamptz
+		lastStatus pgtype.Text
+		started    pgtype.Timestamptz
+		want       string
+		wantErr    string
+	}{
+		{"recent ping", ago(time.Minute), success, pgtype.Timestamptz{}, StatusUp, ""},
+		{"ping within grace", ago(5*time.Minute + 30*time.Second), success, pgtype.Timestamptz{}, StatusUp, ""},
+		{"missed ping", ago(7 * time.Minute), success, pgtype.Timestamptz{}, StatusDown, "no heartbeat received since"},
+		{"no ping yet", pgtype.Timestamptz{}, pgtype.Text{}, pgtype.Timestamptz{}, StatusDown, "no heartbeat received yet"},
+		{"failed run", ago(time.Minute), pgtype.Text{String: HeartbeatFail, Valid: true}, pgtype.Timestamptz{}, StatusDown, "last run reported a failure"},
+		{"running", ago(time.Minute), success, ago(30 * time.Second), StatusUp, ""},
+		{"running longer than grace", ago(time.Minute), success, ago(2 * time.Minute), StatusDown, "still running after"},
+	}
+	for _, tt := range tests {
+		s := db.GetServicesAndOwnersRow{
+			Type:                  TypeHeartbeat,
+			CheckIntervalSeconds:  300,
+			HeartbeatGraceSeconds: 60,
+			LastHeartbeatAt:       tt.lastPing,
+			LastHeartbeatStatus:   tt.lastStatus,
+			HeartbeatStartedAt:    tt.started,
+		}
+		s.CreatedAt = ago(time.Hour)
+		result := (&HeartbeatChecker{}).Check(context.Background(), s)
+		if result.Status != tt.want {
+			t.Errorf("%s: status = %s (%v), want %s", tt.name, result.Status, result.Err, tt.want)
+		}
+		if tt.wantErr != "" && (result.Err == nil || !strings.Contains(result.Err.Error(), tt.wantErr)) {
+			t.Errorf("%s: error = %v, want %q", tt.name, result.Err, tt.wantErr)
+		}
+	}
+}
+
+func TestAlertTargetHidesHeartbeatToken(t *testing.T) {
+	s := db.GetServicesAndOwnersRow{Name: "Backup", Type: TypeHeartbeat, Target: "/heartbeat/secret-token"}
+	alert := newAlert(s, Result{}, notifications.EventServiceDown, "Backup is DOWN", "")
+	if strings.Contains(alert.Target, "secret-token") {
+		t.Errorf("alert target = %q, contains the heartbeat token", alert.Target)
+	}
+	if got := escalationAlert(s, db.Incident{ID: 1}, time.Hour); strings.Contains(got.Message, "secret-token") {
+		t.Errorf("escalation message = %q, contains the heartbeat token", got.Message)
+	}
+
+	s = db.GetServicesAndOwnersRow{Name: "API", Type: TypeHTTP, Target: "https://api.example.com"}
+	if alert := newAlert(s, Result{}, notifications.EventServiceDown, "API is DOWN", ""); alert.Target != s.Target {
+		t.Errorf("alert target = %q, want %q", alert.Target, s.Target)
+	}
+}
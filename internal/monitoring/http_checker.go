@@ -0,0 +1,171 @@
+package monitoring
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"uptime-monitor/internal/database/db"
+)
+
+// HTTPChecker sends the request configured on the service (method, headers,
+// body and auth) and evaluates the response against the service assertions
+// (a 2xx status code by default). When the service has check_certificate set,
+// the peer certificate is also inspected.
+type HTTPChecker struct{}
+
+// certInspection holds the certificate of the last TLS handshake of a check,
+// and the certificate of each host handshaken with while following redirects.
+type certInspection struct {
+	cert   *CertInfo
+	err    error
+	byHost map[string]*CertInfo
+}
+
+// transport returns an HTTP transport that inspects and verifies the peer
+// certificate of every handshake itself, like TLSChecker, so that an invalid
+// or expired certificate is still recorded. The handshake fails when it
+// doesn't verify, before anything is sent.
+func (i *certInspection) transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			i.cert, i.err = inspectCertificate(state.PeerCertificates, state.ServerName)
+			if i.byHost == nil {
+				i.byHost = make(map[string]*CertInfo)
+			}
+			i.byHost[state.ServerName] = i.cert
+			return i.err
+		},
+	}
+	return transport
+}
+
+// Check implements Checker.
+func (c *HTTPChecker) Check(ctx context.Context, s db.GetServicesAndOwnersRow) Result {
+	client := http.Client{
+		Timeout: timeoutFor(s),
+	}
+	var inspection certInspection
+	if s.CheckCertificate {
+		transport := inspection.transport()
+		defer transport.CloseIdleConnections()
+		client.Transport = transport
+	}
+	if !s.FollowRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	assertions, err := loadAssertions(s.ID, s.Assertions)
+	if err != nil {
+		return Result{Status: StatusDown, Err: err}
+	}
+
+	req, err := newRequest(ctx, s)
+	if err != nil {
+		return Result{Status: StatusDown, Err: err}
+	}
+
+	startTime := time.Now()
+	resp, err := client.Do(req)
+	responseTime := time.Since(startTime)
+
+	if err != nil {
+		if inspection.err != nil {
+			return Result{Status: StatusDown, ResponseTime: responseTime, Err: inspection.err, Cert: inspection.cert}
+		}
+		return Result{Status: StatusDown, Err: err}
+	}
+	defer resp.Body.Close()
+
+	result := Result{
+		StatusCode:   resp.StatusCode,
+		ResponseTime: responseTime,
+	}
+
+	var failures []string
+	if msg := assertions.checkStatus(resp.StatusCode); msg != "" {
+		failures = append(failures, msg)
+	}
+	failures = append(failures, assertions.checkHeaders(resp.Header)...)
+
+	if assertions.needsBody() {
+		limit := assertions.bodyLimit()
+		body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("failed to read body: %v", err))
+		} else {
+			if int64(len(body)) > limit {
+				body = body[:limit]
+				if assertions.MaxResponseBytes > 0 {
+					failures = append(failures, fmt.Sprintf("response exceeds %d bytes", assertions.MaxResponseBytes))
+				}
+			}
+			failures = append(failures, assertions.checkBody(body)...)
+		}
+	}
+
+	if len(failures) == 0 {
+		result.Status = StatusUp
+	} else {
+		result.Status = StatusDown
+		result.Err = errors.New(strings.Join(failures, "; "))
+	}
+
+	if s.CheckCertificate && resp.TLS != nil {
+		// The certificate of the final response, inspected during its
+		// handshake; the connection may have been reused after redirects.
+		result.Cert = inspection.byHost[resp.TLS.ServerName]
+	}
+
+	return result
+}
+
+// newRequest builds the HTTP request described by the service.
+func newRequest(ctx context.Context, s db.GetServicesAndOwnersRow) (*http.Request, error) {
+	method := s.HttpMethod
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if s.HttpBody.Valid && s.HttpBody.String != "" {
+		body = strings.NewReader(s.HttpBody.String)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.Target, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.HttpHeaders) > 0 {
+		var headers map[string]string
+		if err := json.Unmarshal(s.HttpHeaders, &headers); err != nil {
+			return nil, fmt.Errorf("invalid http_headers: %w", err)
+		}
+		for name, value := range headers {
+			if strings.EqualFold(name, "Host") {
+				req.Host = value
+				continue
+			}
+			req.Header.Set(name, value)
+		}
+	}
+
+	switch s.HttpAuthType.String {
+	case "basic":
+		req.SetBasicAuth(s.HttpAuthUsername.String, s.HttpAuthPassword.String)
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+s.HttpAuthToken.String)
+	}
+
+	return req, nil
+}
@@ -0,0 +1,144 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Incident statuses stored in incidents.status.
+const (
+	IncidentOpen         = "open"
+	IncidentAcknowledged = "acknowledged"
+	IncidentResolved     = "resolved"
+)
+
+// Incident timeline event kinds stored in incident_events.kind.
+const (
+	EventOpened       = "opened"
+	EventAcknowledged = "acknowledged"
+	EventResolved     = "resolved"
+	EventNote         = "note"
+	EventEscalated    = "escalated"
+)
+
+// Statuses of the incident updates posted for status page visitors, stored
+// in incident_updates.status.
+const (
+	UpdateInvestigating = "investigating"
+	UpdateIdentified    = "identified"
+	UpdateMonitoring    = "monitoring"
+	UpdateResolved      = "resolved"
+)
+
+// UpdateLabels are the incident update statuses as shown to visitors.
+var UpdateLabels = map[string]string{
+	UpdateInvestigating: "Investigating",
+	UpdateIdentified:    "Identified",
+	UpdateMonitoring:    "Monitoring",
+	UpdateResolved:      "Resolved",
+}
+
+// trackIncident opens an incident when a service goes down and resolves it
+// when the service responds again, even if degraded: the outage is over, and
+// paging resolves the page at the same time. It is called once per confirmed
+// state change and returns the unresolved incident the change belongs to, if
+// any, and the incident it resolved.
+//
+// An outage while an incident is already open, such as one opened manually,
+// joins that incident. Manual incidents are not resolved by a recovery, which
+// belongs to them as well.
+func (m *Monitor) trackIncident(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow, status string, result Result) (ongoing, resolved *db.Incident, err error) {
+	switch status {
+	case StatusDown:
+		ongoing, err = openIncident(ctx, q, s, result)
+	case StatusUp, StatusDegraded:
+		if resolved, err = resolveIncident(ctx, q, s, status); err == nil && resolved == nil {
+			ongoing, err = unresolvedIncident(ctx, q, s.ID)
+		}
+	}
+	return ongoing, resolved, err
+}
+
+func openIncident(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow, result Result) (*db.Incident, error) {
+	params := db.OpenIncidentParams{
+		ServiceID: s.ID,
+		Title:     fmt.Sprintf("%s is down", s.Name),
+	}
+	if result.Err != nil {
+		params.FirstError = pgtype.Text{String: result.Err.Error(), Valid: true}
+	}
+
+	incident, err := q.OpenIncident(ctx, params)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("could not open incident: %w", err)
+		}
+
+		// The service already has an unresolved incident
+		existing, err := unresolvedIncident(ctx, q, s.ID)
+		if err != nil || existing == nil {
+			return nil, err
+		}
+		message := "Service is down"
+		if result.Err != nil {
+			message += ": " + result.Err.Error()
+		}
+		if err := addIncidentEvent(ctx, q, existing.ID, EventNote, pgtype.Text{String: message, Valid: true}); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	if err := addIncidentEvent(ctx, q, incident.ID, EventOpened, params.FirstError); err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// unresolvedIncident returns the service's unresolved incident, if any.
+func unresolvedIncident(ctx context.Context, q *db.Queries, serviceID int64) (*db.Incident, error) {
+	incident, err := q.GetUnresolvedIncidentForService(ctx, serviceID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not get unresolved incident: %w", err)
+	}
+	return &incident, nil
+}
+
+func resolveIncident(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow, status string) (*db.Incident, error) {
+	incident, err := q.ResolveServiceIncident(ctx, s.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not resolve incident: %w", err)
+	}
+
+	message := "Service recovered"
+	if status == StatusDegraded {
+		message = "Service recovered with degraded performance"
+	}
+	if err := addIncidentEvent(ctx, q, incident.ID, EventResolved, pgtype.Text{String: message, Valid: true}); err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+func addIncidentEvent(ctx context.Context, q *db.Queries, incidentID int64, kind string, message pgtype.Text) error {
+	_, err := q.CreateIncidentEvent(ctx, db.CreateIncidentEventParams{
+		IncidentID: incidentID,
+		Kind:       kind,
+		Message:    message,
+	})
+	if err != nil {
+		return fmt.Errorf("could not add %s event to incident %d: %w", kind, incidentID, err)
+	}
+	return nil
+}
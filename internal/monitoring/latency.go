@@ -0,0 +1,75 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"uptime-monitor/internal/database/db"
+)
+
+// applyResponseThreshold downgrades a successful result to degraded when its
+// response time exceeds the service threshold.
+func applyResponseThreshold(s db.GetServicesAndOwnersRow, result *Result) {
+	if result.Status != StatusUp || result.ResponseTime <= 0 {
+		return
+	}
+
+	responseMs := int32(result.ResponseTime.Milliseconds())
+	if s.DegradedThresholdMs.Valid && responseMs > s.DegradedThresholdMs.Int32 {
+		result.Status = StatusDegraded
+		result.Err = fmt.Errorf("response time %dms exceeds %dms threshold", responseMs, s.DegradedThresholdMs.Int32)
+	}
+}
+
+// evaluateLatency downgrades a successful result to degraded when the rolling
+// p95 of the central checks, including this one, exceeds the service threshold.
+func (m *Monitor) evaluateLatency(ctx context.Context, s db.GetServicesAndOwnersRow, result *Result) error {
+	if result.Status != StatusUp || result.ResponseTime <= 0 {
+		return nil
+	}
+
+	if !s.DegradedP95ThresholdMs.Valid || s.DegradedP95Window <= 0 {
+		return nil
+	}
+
+	// The current check is not saved yet, so fetch one less and add it.
+	recent, err := m.q.GetRecentResponseTimesForService(ctx, db.GetRecentResponseTimesForServiceParams{
+		ServiceID: s.ID,
+		Limit:     s.DegradedP95Window - 1,
+	})
+	if err != nil {
+		return err
+	}
+
+	samples := []time.Duration{result.ResponseTime}
+	for _, ms := range recent {
+		if ms.Valid {
+			samples = append(samples, time.Duration(ms.Int32)*time.Millisecond)
+		}
+	}
+
+	p95 := percentile(samples, 95).Milliseconds()
+	if p95 > int64(s.DegradedP95ThresholdMs.Int32) {
+		result.Status = StatusDegraded
+		result.Err = fmt.Errorf("p95 response time %dms over the last %d checks exceeds %dms threshold",
+			p95, len(samples), s.DegradedP95ThresholdMs.Int32)
+	}
+	return nil
+}
+
+// percentile returns the nearest-rank percentile of the samples.
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,88 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MaxRecurringMaintenance is the longest occurrence of a recurring
+// maintenance window.
+const MaxRecurringMaintenance = 24 * time.Hour
+
+// InMaintenance reports whether the service has a maintenance window in
+// progress at the given time.
+func InMaintenance(ctx context.Context, q *db.Queries, serviceID int64, at time.Time) (bool, error) {
+	windows, err := q.GetMaintenanceWindowsForService(ctx, db.GetMaintenanceWindowsForServiceParams{
+		ServiceID: serviceID,
+		At:        pgtype.Timestamptz{Time: at, Valid: true},
+	})
+	if err != nil {
+		return false, fmt.Errorf("could not get maintenance windows: %w", err)
+	}
+
+	for _, w := range windows {
+		active, err := MaintenanceActive(w, at)
+		if err != nil {
+			return false, fmt.Errorf("maintenance window %d: %w", w.ID, err)
+		}
+		if active {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MaintenanceActive reports whether the window, or one of its occurrences if
+// it recurs, is in progress at the given time.
+func MaintenanceActive(w db.MaintenanceWindow, at time.Time) (bool, error) {
+	if w.CancelledAt.Valid || at.Before(w.StartsAt.Time) {
+		return false, nil
+	}
+	duration := time.Duration(w.DurationSeconds) * time.Second
+	if !w.Recurrence.Valid {
+		return at.Before(w.StartsAt.Time.Add(duration)), nil
+	}
+
+	loc, err := loadLocation(w.Timezone)
+	if err != nil {
+		return false, err
+	}
+	start := w.StartsAt.Time.Truncate(time.Minute).In(loc)
+	recurrence, err := ParseRecurrence(w.Recurrence.String, start)
+	if err != nil {
+		return false, err
+	}
+
+	// The window is active if its latest occurrence up to now started within
+	// the last duration. Recurring occurrences are capped at a day.
+	if duration > MaxRecurringMaintenance {
+		duration = MaxRecurringMaintenance
+	}
+	latest := at
+	if w.RecurrenceUntil.Valid && w.RecurrenceUntil.Time.Before(latest) {
+		latest = w.RecurrenceUntil.Time
+	}
+	occurrence, ok := recurrence.Latest(latest.In(loc), at.Add(-duration).In(loc))
+	return ok && !occurrence.Before(start), nil
+}
+
+// locations caches the time zones of maintenance windows by name, as
+// time.LoadLocation reads them from disk on every call.
+var locations sync.Map
+
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
@@ -2,118 +2,491 @@ package monitoring
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"net/http"
 	"strings"
+	"sync"
 	"time"
 	"uptime-monitor/internal/config"
 	"uptime-monitor/internal/database/db"
 	"uptime-monitor/internal/notifications"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Monitor holds the dependencies for the monitoring worker.
 type Monitor struct {
-	q         *db.Queries
-	notifier  *notifications.EmailNotifier
-	lastCheck map[int64]time.Time // In-memory cache to respect check intervals
+	pool  *pgxpool.Pool // Used to record each check's outcome in one transaction
+	q     *db.Queries
+	probe *Probe // Runs the checker for each service type
+
+	notifyMaxAttempts int // Delivery attempts for each queued notification
+
+	instanceID string // Owner of the check leases taken by this replica
+
+	concurrency     int           // Number of workers running checks
+	jitter          time.Duration // Maximum random delay added to each check
+	refreshInterval time.Duration // How often the service list is reloaded
+
+	mu     sync.Mutex                // Guards queue and checks
+	queue  checkQueue                // Services ordered by next run time
+	checks map[int64]*scheduledCheck // Scheduled services by ID
+	wake   chan struct{}             // Signals that the head of the queue changed
+
+	workers      sync.WaitGroup     // In-flight workers
+	stopped      chan struct{}      // Closed when the scheduling loop has exited
+	checksCtx    context.Context    // Parent of every check, outlives the Start context
+	cancelChecks context.CancelFunc // Aborts in-flight checks when shutdown times out
 }
 
-// NewMonitor creates a new Monitor instance.
-func NewMonitor(cfg *config.Config, q *db.Queries) *Monitor {
-	return &Monitor{
-		q:         q,
-		notifier:  notifications.NewEmailNotifier(cfg),
-		lastCheck: make(map[int64]time.Time),
+// NewMonitor creates a new Monitor instance. Notifications are queued in the
+// outbox for a notifications.Dispatcher to deliver.
+func NewMonitor(cfg *config.Config, pool *pgxpool.Pool) *Monitor {
+	m := &Monitor{
+		pool:              pool,
+		q:                 db.New(pool),
+		probe:             NewProbe(),
+		notifyMaxAttempts: cfg.NotifyMaxAttempts,
+		instanceID:        cfg.InstanceID,
+		concurrency:       cfg.MonitorConcurrency,
+		jitter:            cfg.MonitorJitter,
+		refreshInterval:   cfg.MonitorRefreshInterval,
+		checks:            make(map[int64]*scheduledCheck),
+		wake:              make(chan struct{}, 1),
+		stopped:           make(chan struct{}),
+	}
+	m.checksCtx, m.cancelChecks = context.WithCancel(context.Background())
+	if m.concurrency <= 0 {
+		m.concurrency = 1
+	}
+	if m.refreshInterval <= 0 {
+		m.refreshInterval = 30 * time.Second
 	}
+	if m.notifyMaxAttempts <= 0 {
+		m.notifyMaxAttempts = 1
+	}
+	return m
 }
 
-// Start begins the monitoring loop.
-func (m *Monitor) Start() {
-	log.Println("Monitoring worker started")
-	ticker := time.NewTicker(15 * time.Second) // Ticker runs more frequently
-	defer ticker.Stop()
+// Start runs the monitoring loop until ctx is cancelled. Due services are
+// handed to a fixed pool of workers; when all workers are busy, scheduling
+// waits for one to finish. Checks already running are left to Shutdown.
+func (m *Monitor) Start(ctx context.Context) {
+	defer close(m.stopped)
+	log.Printf("Monitoring worker started with %d workers", m.concurrency)
+
+	work := make(chan db.GetServicesAndOwnersRow)
+	defer close(work)
+	for i := 0; i < m.concurrency; i++ {
+		m.workers.Add(1)
+		go m.worker(work)
+	}
+
+	m.refreshServices(ctx)
+	refresh := time.NewTicker(m.refreshInterval)
+	defer refresh.Stop()
+	timer := time.NewTimer(m.untilNext())
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			m.checkAllServices()
+		case <-ctx.Done():
+			log.Println("Monitoring worker stopped scheduling new checks")
+			return
+		case <-refresh.C:
+			m.refreshServices(ctx)
+		case <-m.wake:
+		case <-timer.C:
+			for _, service := range m.dueChecks(time.Now()) {
+				select {
+				case work <- service:
+				case <-ctx.Done():
+					m.finishCheck(service.ID)
+				}
+			}
 		}
+		timer.Reset(m.untilNext())
 	}
 }
 
-func (m *Monitor) checkAllServices() {
-	services, err := m.q.GetServicesAndOwners(context.Background())
+// Shutdown waits for the scheduling loop to exit and for in-flight checks
+// (including their notifications) to finish. If ctx expires first, the
+// remaining checks are cancelled and ctx's error is returned.
+func (m *Monitor) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		<-m.stopped
+		m.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("Monitoring worker finished in-flight checks")
+		return nil
+	case <-ctx.Done():
+		m.cancelChecks()
+		return ctx.Err()
+	}
+}
+
+// refreshServices reloads the service list into the schedule.
+func (m *Monitor) refreshServices(ctx context.Context) {
+	services, err := m.q.GetServicesAndOwners(ctx)
 	if err != nil {
 		log.Printf("Error fetching services and owners: %v", err)
 		return
 	}
+	m.syncServices(services)
+}
 
-	for _, service := range services {
-		// Respect the user-defined check interval
-		if time.Since(m.lastCheck[service.ID]) > time.Duration(service.CheckIntervalSeconds)*time.Second {
-			go m.checkService(service)
-			m.lastCheck[service.ID] = time.Now()
-		}
+// worker runs checks until the work channel is closed.
+func (m *Monitor) worker(work <-chan db.GetServicesAndOwnersRow) {
+	defer m.workers.Done()
+	for s := range work {
+		ctx, cancel := context.WithTimeout(m.checksCtx, checkDeadline(s))
+		m.runCheck(ctx, s)
+		cancel()
+		m.finishCheck(s.ID)
 	}
 }
 
-func (m *Monitor) checkService(s db.GetServicesAndOwnersRow) {
-	client := http.Client{
-		Timeout: 10 * time.Second,
+// runCheck claims the lease on the service so that no other replica checks
+// it at the same time, then checks it using its latest configuration and
+// state. Services that are not due in the database (e.g. another replica
+// checked them first) are skipped.
+func (m *Monitor) runCheck(ctx context.Context, scheduled db.GetServicesAndOwnersRow) {
+	row, err := m.q.ClaimServiceCheck(ctx, db.ClaimServiceCheckParams{
+		ID:           scheduled.ID,
+		LeaseSeconds: int32(checkDeadline(scheduled).Seconds()),
+		InstanceID:   m.instanceID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR: Could not claim service %d: %v", scheduled.ID, err)
+		return
 	}
 
-	currentStatus := ""
-	params := db.CreateStatusCheckParams{
-		ServiceID: s.ID,
+	defer func() {
+		// Release even if the check ran out of time; the lease expires anyway.
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		err := m.q.ReleaseServiceCheck(releaseCtx, db.ReleaseServiceCheckParams{
+			ID:       scheduled.ID,
+			LockedBy: pgtype.Text{String: m.instanceID, Valid: true},
+		})
+		if err != nil {
+			log.Printf("ERROR: Could not release service %d: %v", scheduled.ID, err)
+		}
+	}()
+
+	m.checkService(ctx, db.GetServicesAndOwnersRow(row))
+}
+
+func (m *Monitor) checkService(ctx context.Context, s db.GetServicesAndOwnersRow) {
+	maintenance, err := InMaintenance(ctx, m.q, s.ID, time.Now())
+	if err != nil {
+		log.Printf("ERROR: Could not check maintenance windows of service %d: %v", s.ID, err)
 	}
 
-	startTime := time.Now()
-	resp, err := client.Get(s.Target)
-	responseTime := time.Since(startTime)
+	if maintenance {
+		if err := m.q.StartMaintenanceStatus(ctx, s.ID); err != nil {
+			log.Printf("ERROR: Could not record maintenance of service %d: %v", s.ID, err)
+		}
+	}
 
+	// Services checked only by remote agents just have their state evaluated.
+	if !s.CheckLocally && len(s.Locations) > 0 {
+		if maintenance {
+			return
+		}
+		err := m.inTx(ctx, func(q *db.Queries) error {
+			if err := q.EndMaintenanceStatus(ctx, s.ID); err != nil {
+				return fmt.Errorf("failed to record end of maintenance: %w", err)
+			}
+			return m.updateState(ctx, q, s, Result{})
+		})
+		if err != nil {
+			log.Printf("ERROR: Could not update state of service %d: %v", s.ID, err)
+		}
+		return
+	}
+
+	result, err := m.probe.Check(ctx, s)
 	if err != nil {
-		currentStatus = "down"
-		params.Status = currentStatus
-		params.ErrorMessage = pgtype.Text{String: err.Error(), Valid: true}
-	} else {
-		defer resp.Body.Close()
-		params.StatusCode = pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true}
-		params.ResponseTimeMs = pgtype.Int4{Int32: int32(responseTime.Milliseconds()), Valid: true}
+		log.Printf("ERROR: Could not check service %d: %v", s.ID, err)
+		return
+	}
 
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			currentStatus = "up"
-		} else {
-			currentStatus = "down"
-			params.ErrorMessage = pgtype.Text{String: fmt.Sprintf("Non-2xx status code: %d", resp.StatusCode), Valid: true}
+	// During maintenance the check is recorded for reference only: it does
+	// not count towards the service's state, latency or uptime, and nothing
+	// is notified.
+	if maintenance {
+		params := NewReport(s.ID, result).MaintenanceCheckParams(LocationCentral)
+		if _, err := m.q.CreateStatusCheck(ctx, params); err != nil {
+			log.Printf("ERROR: Could not record check of service %d: %v", s.ID, err)
 		}
-		params.Status = currentStatus
+		return
 	}
 
-	// --- State Change Detection & Notification ---
-	previousStatus, err := m.q.GetLatestStatusCheckForService(context.Background(), s.ID)
-	// sql.ErrNoRows is okay, means it's the first check ever.
-	if err != nil && err != sql.ErrNoRows {
-		log.Printf("ERROR: Could not get previous status for service %d: %v", s.ID, err)
-		return // Don't save the new check if we can't verify the old one
+	if err := m.evaluateLatency(ctx, s, &result); err != nil {
+		log.Printf("ERROR: Could not evaluate latency thresholds for service %d: %v", s.ID, err)
 	}
 
-	// If status has changed, send notification
-	if previousStatus != currentStatus && err != sql.ErrNoRows {
-		log.Printf("STATE CHANGE for %s: %s -> %s. Sending notification.", s.Name, previousStatus, currentStatus)
-		subject := fmt.Sprintf("Uptime Alert: %s is %s", s.Name, strings.ToUpper(currentStatus))
-		body := fmt.Sprintf("Your service '%s' (%s) is now %s.\n\nChecked at: %s", s.Name, s.Target, currentStatus, time.Now().Format(time.RFC1123))
-		if err := m.notifier.SendNotification(s.OwnerEmail, subject, body); err != nil {
-			log.Printf("ERROR: Failed to send notification for service %d: %v", s.ID, err)
+	// The check, the state change and its notifications are saved together,
+	// so an alert is never lost nor sent for a change that wasn't recorded.
+	err = m.inTx(ctx, func(q *db.Queries) error {
+		// --- Certificate Expiry Notification ---
+		if result.Cert != nil {
+			if err := m.checkCertificateExpiry(ctx, q, s, result.Cert); err != nil {
+				return err
+			}
+		}
+
+		// --- Save the current check to the database ---
+		// Every raw attempt is recorded, including unconfirmed failures.
+		params := NewReport(s.ID, result).StatusCheckParams(LocationCentral)
+		if _, err := q.CreateStatusCheck(ctx, params); err != nil {
+			return fmt.Errorf("failed to save status check: %w", err)
+		}
+		if err := q.EndMaintenanceStatus(ctx, s.ID); err != nil {
+			return fmt.Errorf("failed to record end of maintenance: %w", err)
 		}
+
+		// --- State Change Detection & Notification ---
+		return m.updateState(ctx, q, s, result)
+	})
+	if err != nil {
+		log.Printf("ERROR: Could not record check of service %d: %v", s.ID, err)
 	}
+}
 
-	// --- Save the current check to the database ---
-	_, dbErr := m.q.CreateStatusCheck(context.Background(), params)
-	if dbErr != nil {
-		log.Printf("ERROR: Failed to save status check for service %d: %v", s.ID, dbErr)
+// inTx runs fn with queries bound to a new transaction, and commits it if fn succeeds.
+func (m *Monitor) inTx(ctx context.Context, fn func(q *db.Queries) error) error {
+	tx, err := m.pool.Begin(ctx)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback(ctx) // No-op once committed
+
+	if err := fn(m.q.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// updateState changes the confirmed status of the service once enough
+// consecutive checks agree, and notifies the owner of the change unless the
+// service is flapping or unreachable because of a dependency. Until then the
+// service is re-checked at its retry interval. Services checked from several
+// locations are confirmed by quorum instead.
+func (m *Monitor) updateState(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow, result Result) error {
+	multiLocation := len(s.Locations) > 0
+	if multiLocation {
+		var err error
+		if result, err = m.quorumResult(ctx, q, s); err != nil {
+			return fmt.Errorf("could not evaluate quorum: %w", err)
+		}
+	}
+	currentStatus := result.Status
+
+	// A service is unreachable, not down, while a service it depends on is
+	// down; only the root cause is notified.
+	var downParents []string
+	if currentStatus == StatusDown {
+		var err error
+		if downParents, err = rootCause(ctx, q, s); err != nil {
+			return err
+		}
+		if len(downParents) > 0 {
+			currentStatus = StatusUnreachable
+		}
+	}
+
+	// First check ever: record the status without notifying.
+	if !s.Status.Valid {
+		_, err := m.setStatus(ctx, q, s, currentStatus)
+		return err
+	}
+
+	// Flapping is judged from the central checks, so not for agent-only services.
+	flapping := false
+	if s.CheckLocally {
+		var err error
+		if flapping, err = m.trackFlapping(ctx, q, s, result); err != nil {
+			return err
+		}
+	}
+
+	previousStatus := s.Status.String
+	if previousStatus == currentStatus {
+		return nil
+	}
+
+	needed := int(s.SuccessesBeforeUp)
+	if result.Status == StatusDown {
+		needed = int(s.RetriesBeforeDown) + 1
+	}
+
+	if needed > 1 && !multiLocation {
+		recent, err := q.GetRecentStatusesForService(ctx, db.GetRecentStatusesForServiceParams{
+			ServiceID: s.ID,
+			Limit:     int32(needed),
+		})
+		if err != nil {
+			return fmt.Errorf("could not get recent checks: %w", err)
+		}
+
+		consecutive := 0
+		for _, status := range recent {
+			if status != result.Status {
+				break
+			}
+			consecutive++
+		}
+
+		if consecutive < needed {
+			log.Printf("PENDING STATE CHANGE for %s: %s -> %s (%d/%d). Retrying.", s.Name, previousStatus, currentStatus, consecutive, needed)
+			return m.scheduleRetry(ctx, q, s)
+		}
+	}
+
+	updated, err := m.setStatus(ctx, q, s, currentStatus)
+	if err != nil || !updated {
+		return err
+	}
+	ongoing, resolved, err := m.trackIncident(ctx, q, s, currentStatus, result)
+	if err != nil {
+		return err
+	}
+
+	if flapping {
+		log.Printf("STATE CHANGE for %s: %s -> %s. Not notifying while flapping.", s.Name, previousStatus, currentStatus)
+		return nil
+	}
+	if currentStatus == StatusUnreachable {
+		log.Printf("STATE CHANGE for %s: %s -> %s because %s is down. Not notifying.", s.Name, previousStatus, currentStatus, strings.Join(downParents, ", "))
+		return nil
+	}
+	// Recovering from an outage that was never notified, i.e. without an
+	// incident of its own
+	if previousStatus == StatusUnreachable && currentStatus != StatusDown && resolved == nil {
+		log.Printf("STATE CHANGE for %s: %s -> %s. Not notifying.", s.Name, previousStatus, currentStatus)
+		return nil
+	}
+
+	log.Printf("STATE CHANGE for %s: %s -> %s. Queueing notification.", s.Name, previousStatus, currentStatus)
+	subject := fmt.Sprintf("Uptime Alert: %s is %s", s.Name, strings.ToUpper(currentStatus))
+	body := fmt.Sprintf("Your service '%s' (%s) is now %s.\n\nChecked at: %s", s.Name, alertTarget(s), currentStatus, time.Now().Format(time.RFC1123))
+	if result.Err != nil {
+		body += fmt.Sprintf("\nReason: %s", result.Err.Error())
+	}
+	if currentStatus == StatusDown {
+		affected, err := affectedServices(ctx, q, s)
+		if err != nil {
+			return err
+		}
+		if affected != "" {
+			body += "\n\n" + affected
+		}
+	}
+	alert := newAlert(s, result, statusEvents[currentStatus], subject, body)
+	alert.Status = currentStatus
+	alert.PreviousStatus = previousStatus
+	if ongoing != nil {
+		alert.IncidentID = ongoing.ID
+	}
+	if resolved != nil {
+		alert.ResolvedIncidentID = resolved.ID
+		alert.IncidentDuration = time.Duration(resolved.DurationSeconds.Int64) * time.Second
+	}
+
+	// With an escalation policy, its levels are notified instead of the
+	// service's channels. An outage starts escalating its incident unless the
+	// incident, e.g. a manual one, was escalated already, in which case the
+	// levels notified so far are told, as they are of the recovery. Other
+	// changes were never escalated, so nobody is told about them.
+	if s.EscalationPolicyID.Valid {
+		switch {
+		case ongoing != nil && currentStatus == StatusDown && ongoing.EscalationLevel == 0:
+			return escalateIncident(ctx, q, s, *ongoing, &alert, m.notifyMaxAttempts)
+		case ongoing != nil && ongoing.EscalationLevel > 0:
+			return notifyEscalated(ctx, q, s, *ongoing, alert, m.notifyMaxAttempts)
+		case resolved != nil && resolved.EscalationLevel > 0:
+			return notifyEscalated(ctx, q, s, *resolved, alert, m.notifyMaxAttempts)
+		}
+		log.Printf("STATE CHANGE for %s: %s -> %s. Not notifying outside the escalation policy.", s.Name, previousStatus, currentStatus)
+		return nil
+	}
+	return m.notify(ctx, q, s, alert)
+}
+
+// setStatus persists the confirmed status of a service and records the change
+// for uptime reporting. It returns false if the status was already recorded
+// (e.g. by another replica).
+func (m *Monitor) setStatus(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow, status string) (bool, error) {
+	updated, err := q.UpdateServiceStatus(ctx, db.UpdateServiceStatusParams{
+		ID:     s.ID,
+		Status: pgtype.Text{String: status, Valid: true},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to update status: %w", err)
+	}
+	if updated == 0 {
+		return false, nil
+	}
+	if err := q.CreateStatusChange(ctx, db.CreateStatusChangeParams{ServiceID: s.ID, Status: status}); err != nil {
+		return false, fmt.Errorf("failed to record status change: %w", err)
+	}
+	return true, nil
+}
+
+// checkCertificateExpiry sends a "certificate expiring" alert when the
+// certificate reaches one of the service's thresholds for the first time, and
+// a "certificate renewed" alert when it no longer reaches any.
+func (m *Monitor) checkCertificateExpiry(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow, cert *CertInfo) error {
+	var previousDays *int
+	lastDays, err := q.GetLatestCertDaysForService(ctx, s.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("could not get previous certificate check: %w", err)
+	}
+	if err == nil && lastDays.Valid {
+		days := int(lastDays.Int32)
+		previousDays = &days
+	}
+
+	thresholds := s.CertExpiryThresholds
+	if len(thresholds) == 0 {
+		thresholds = DefaultCertExpiryThresholds
+	}
+
+	var event, subject, intro string
+	if threshold, crossed := crossedThreshold(thresholds, previousDays, cert.DaysRemaining); crossed {
+		log.Printf("CERTIFICATE for %s expires in %d days (threshold %d). Queueing notification.", s.Name, cert.DaysRemaining, threshold)
+		event = notifications.EventCertificateExpiring
+		subject = fmt.Sprintf("Certificate Alert: %s expires in %d days", s.Name, cert.DaysRemaining)
+		if cert.DaysRemaining < 0 {
+			subject = fmt.Sprintf("Certificate Alert: %s has EXPIRED", s.Name)
+		}
+		intro = fmt.Sprintf("The TLS certificate for your service '%s' (%s) expires on", s.Name, alertTarget(s))
+	} else if certificateRenewed(thresholds, previousDays, cert.DaysRemaining) {
+		log.Printf("CERTIFICATE for %s was renewed and expires in %d days. Queueing notification.", s.Name, cert.DaysRemaining)
+		event = notifications.EventCertificateRenewed
+		subject = fmt.Sprintf("Certificate Renewed: %s expires in %d days", s.Name, cert.DaysRemaining)
+		intro = fmt.Sprintf("The TLS certificate for your service '%s' (%s) was renewed and now expires on", s.Name, alertTarget(s))
+	} else {
+		return nil
+	}
+
+	body := fmt.Sprintf("%s %s (%d days remaining).\n\nIssuer: %s\nNames: %s\n\nChecked at: %s",
+		intro, cert.NotAfter.Format(time.RFC1123), cert.DaysRemaining, cert.Issuer, strings.Join(cert.SANs, ", "), time.Now().Format(time.RFC1123))
+	alert := newAlert(s, Result{}, event, subject, body)
+	alert.CertDaysRemaining = cert.DaysRemaining
+	alert.CertExpiresAt = cert.NotAfter
+	return m.notify(ctx, q, s, alert)
 }
@@ -0,0 +1,73 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/notifications"
+)
+
+// statusEvents maps a new service status to its webhook event type.
+var statusEvents = map[string]string{
+	StatusUp:       notifications.EventServiceUp,
+	StatusDegraded: notifications.EventServiceDegraded,
+	StatusDown:     notifications.EventServiceDown,
+}
+
+// newAlert returns an alert about the service with the details of the result.
+func newAlert(s db.GetServicesAndOwnersRow, result Result, event, subject, message string) notifications.Alert {
+	alert := notifications.Alert{
+		Event:        event,
+		ServiceID:    s.ID,
+		ServiceName:  s.Name,
+		Target:       alertTarget(s),
+		StatusCode:   result.StatusCode,
+		ResponseTime: result.ResponseTime,
+		Subject:      subject,
+		Message:      message,
+		Time:         time.Now(),
+	}
+	if result.Err != nil {
+		alert.Error = result.Err.Error()
+	}
+	return alert
+}
+
+// alertTarget returns the target of the service as shown in alerts. The
+// target of a heartbeat service is its ping URL, which contains the secret
+// token, so it is left out.
+func alertTarget(s db.GetServicesAndOwnersRow) string {
+	if s.Type == TypeHeartbeat {
+		return TypeHeartbeat
+	}
+	return s.Target
+}
+
+// notify queues the alert for every enabled channel that applies to the
+// service, using q so that it is committed with the change it reports.
+// Without any channel, the fallback recipient is notified by email.
+func (m *Monitor) notify(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow, alert notifications.Alert) error {
+	channels, err := q.GetNotificationChannelsForService(ctx, s.ID)
+	if err != nil {
+		return fmt.Errorf("could not get notification channels: %w", err)
+	}
+	if err := notifications.Enqueue(ctx, q, channels, fallbackChannel(s), alert, m.notifyMaxAttempts); err != nil {
+		return fmt.Errorf("could not queue notification: %w", err)
+	}
+	return nil
+}
+
+// fallbackChannel returns where alerts go when no channel applies: whoever is
+// on call for the service's schedule when the alert is raised, or else
+// the owner.
+func fallbackChannel(s db.GetServicesAndOwnersRow) db.NotificationChannel {
+	if s.OncallScheduleID.Valid {
+		return db.NotificationChannel{
+			Type:   notifications.ChannelOnCall,
+			Target: strconv.FormatInt(s.OncallScheduleID.Int64, 10),
+		}
+	}
+	return db.NotificationChannel{Type: notifications.ChannelEmail, Target: s.OwnerEmail}
+}
@@ -0,0 +1,114 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// LocationCentral tags the checks run by the monitor itself, as opposed to
+// those reported by remote probe agents.
+const LocationCentral = "central"
+
+// Probe runs a single check of a service with the checker for its type. It
+// has no database dependency so remote agents can use it too.
+type Probe struct {
+	checkers map[string]Checker
+}
+
+// NewProbe creates a Probe with the checkers for every service type.
+func NewProbe() *Probe {
+	return &Probe{checkers: defaultCheckers()}
+}
+
+// Check runs the checker for the service type and applies the response time
+// threshold of the service.
+func (p *Probe) Check(ctx context.Context, s db.GetServicesAndOwnersRow) (Result, error) {
+	checker, ok := p.checkers[s.Type]
+	if !ok {
+		return Result{}, fmt.Errorf("unknown type %q", s.Type)
+	}
+	result := checker.Check(ctx, s)
+	applyResponseThreshold(s, &result)
+	return result, nil
+}
+
+// Report is the serializable form of a Result, sent by agents to the API.
+type Report struct {
+	ServiceID         int64    `json:"service_id" binding:"required"`
+	Status            string   `json:"status" binding:"required,oneof=up degraded down"`
+	StatusCode        int      `json:"status_code,omitempty"`
+	ResponseTimeMs    int      `json:"response_time_ms,omitempty"`
+	ErrorMessage      string   `json:"error_message,omitempty"`
+	CertDaysRemaining *int     `json:"cert_days_remaining,omitempty"`
+	CertIssuer        string   `json:"cert_issuer,omitempty"`
+	CertSANs          []string `json:"cert_sans,omitempty"`
+	ExitCode          *int     `json:"exit_code,omitempty"`
+	Output            string   `json:"output,omitempty"`
+}
+
+// NewReport converts the result of a check of the given service.
+func NewReport(serviceID int64, r Result) Report {
+	report := Report{
+		ServiceID:      serviceID,
+		Status:         r.Status,
+		StatusCode:     r.StatusCode,
+		ResponseTimeMs: int(r.ResponseTime.Milliseconds()),
+		ExitCode:       r.ExitCode,
+		Output:         r.Output,
+	}
+	if r.Err != nil {
+		report.ErrorMessage = r.Err.Error()
+	}
+	if r.Cert != nil {
+		days := r.Cert.DaysRemaining
+		report.CertDaysRemaining = &days
+		report.CertIssuer = r.Cert.Issuer
+		report.CertSANs = r.Cert.SANs
+	}
+	return report
+}
+
+// StatusCheckParams returns the row to record for the report at the given location.
+func (r Report) StatusCheckParams(location string) db.CreateStatusCheckParams {
+	params := db.CreateStatusCheckParams{
+		ServiceID: r.ServiceID,
+		Status:    r.Status,
+		Location:  location,
+	}
+	if r.StatusCode != 0 {
+		params.StatusCode = pgtype.Int4{Int32: int32(r.StatusCode), Valid: true}
+	}
+	if r.ResponseTimeMs > 0 {
+		params.ResponseTimeMs = pgtype.Int4{Int32: int32(r.ResponseTimeMs), Valid: true}
+	}
+	if r.ErrorMessage != "" {
+		params.ErrorMessage = pgtype.Text{String: r.ErrorMessage, Valid: true}
+	}
+	if r.CertDaysRemaining != nil {
+		params.CertDaysRemaining = pgtype.Int4{Int32: int32(*r.CertDaysRemaining), Valid: true}
+		params.CertIssuer = pgtype.Text{String: r.CertIssuer, Valid: true}
+		params.CertSans = r.CertSANs
+	}
+	if r.ExitCode != nil {
+		params.ExitCode = pgtype.Int4{Int32: int32(*r.ExitCode), Valid: true}
+	}
+	if r.Output != "" {
+		params.Payload = pgtype.Text{String: r.Output, Valid: true}
+	}
+	return params
+}
+
+// MaintenanceCheckParams returns the parameters to record the report as a
+// check run during a maintenance window. The certificate is left out so that
+// an expiry threshold crossed during the window is still notified after it.
+func (r Report) MaintenanceCheckParams(location string) db.CreateStatusCheckParams {
+	params := r.StatusCheckParams(location)
+	params.Status = StatusMaintenance
+	params.CertDaysRemaining = pgtype.Int4{}
+	params.CertIssuer = pgtype.Text{}
+	params.CertSans = nil
+	return params
+}
@@ -0,0 +1,71 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// quorumResult combines the latest check from each location of the service.
+// The service is down when at least quorum locations report it down, and
+// degraded when at least quorum locations report it down or degraded.
+// Locations that have not reported within two intervals are ignored, and the
+// service is down when none has, since it is no longer monitored at all.
+func (m *Monitor) quorumResult(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow) (Result, error) {
+	since := time.Now().Add(-2 * interval(s))
+	latest, err := q.GetLatestStatusPerLocation(ctx, db.GetLatestStatusPerLocationParams{
+		ServiceID: s.ID,
+		CheckedAt: pgtype.Timestamptz{Time: since, Valid: true},
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	expected := make(map[string]bool, len(s.Locations)+1)
+	for _, location := range s.Locations {
+		expected[location] = true
+	}
+	if s.CheckLocally {
+		expected[LocationCentral] = true
+	}
+
+	var down, degraded []string
+	reporting := 0
+	for _, row := range latest {
+		if !expected[row.Location] {
+			continue
+		}
+		reporting++
+		switch row.Status {
+		case StatusDown:
+			down = append(down, row.Location)
+		case StatusDegraded:
+			degraded = append(degraded, row.Location)
+		}
+	}
+
+	if reporting == 0 {
+		return Result{Status: StatusDown, Err: fmt.Errorf("no results from any location since %s",
+			since.UTC().Format(time.RFC3339))}, nil
+	}
+
+	quorum := int(s.Quorum)
+	if quorum < 1 {
+		quorum = 1
+	}
+
+	switch {
+	case len(down) >= quorum:
+		return Result{Status: StatusDown, Err: fmt.Errorf("down from %d of %d locations: %s",
+			len(down), reporting, strings.Join(down, ", "))}, nil
+	case len(down)+len(degraded) >= quorum:
+		return Result{Status: StatusDegraded, Err: fmt.Errorf("degraded from %d of %d locations: %s",
+			len(down)+len(degraded), reporting, strings.Join(append(down, degraded...), ", "))}, nil
+	default:
+		return Result{Status: StatusUp}, nil
+	}
+}
@@ -0,0 +1,378 @@
+package monitoring
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence decides when the occurrences of a recurring maintenance window
+// start. It is either a five-field cron expression ("0 2 * * SUN") or an
+// iCalendar RRULE ("FREQ=WEEKLY;BYDAY=SU"), whose occurrences start at the
+// time of day of the window's start.
+type Recurrence interface {
+	// Latest returns the start of the latest occurrence at or before t, if it
+	// is after after. Both are in the time zone the recurrence is evaluated
+	// in. It looks at the days between them rather than at every minute.
+	Latest(t, after time.Time) (time.Time, bool)
+}
+
+// ParseRecurrence parses a cron expression or an RRULE, optionally prefixed
+// with "RRULE:". start is the start of the first occurrence, in the time zone
+// the recurrence is evaluated in.
+func ParseRecurrence(expr string, start time.Time) (Recurrence, error) {
+	expr = strings.TrimSpace(expr)
+	upper := strings.ToUpper(expr)
+	if strings.HasPrefix(upper, "RRULE:") || strings.HasPrefix(upper, "FREQ=") {
+		return parseRRule(strings.TrimPrefix(upper, "RRULE:"), start)
+	}
+	return parseCron(expr)
+}
+
+// cronSchedule is a parsed cron expression: the allowed values of each field.
+type cronSchedule struct {
+	dom, month, dow map[int]bool
+	domAny, dowAny  bool
+
+	// The allowed minutes and hours, latest first
+	minutes, hours []int
+}
+
+var (
+	cronMonthNames = map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}
+	cronDayNames = map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}
+)
+
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+
+	var c cronSchedule
+	minute, err := parseCronField(fields[0], 0, 59, nil)
+	if err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	hour, err := parseCronField(fields[1], 0, 23, nil)
+	if err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	// 7 is accepted as Sunday, as in most cron implementations.
+	if c.dow, err = parseCronField(fields[4], 0, 7, cronDayNames); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	if c.dow[7] {
+		c.dow[0] = true
+	}
+	c.domAny = covers(c.dom, 1, 31)
+	c.dowAny = covers(c.dow, 0, 6)
+	c.minutes = descending(minute)
+	c.hours = descending(hour)
+	return &c, nil
+}
+
+// descending returns the values of a cron field, latest first.
+func descending(allowed map[int]bool) []int {
+	values := make([]int, 0, len(allowed))
+	for n := range allowed {
+		values = append(values, n)
+	}
+	slices.Sort(values)
+	slices.Reverse(values)
+	return values
+}
+
+// covers reports whether a cron field allows every value from min to max, so
+// that "*/1" or "1-31" is unrestricted like "*".
+func covers(allowed map[int]bool, min, max int) bool {
+	for n := min; n <= max; n++ {
+		if !allowed[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCronField parses a comma-separated list of "*", values, ranges and
+// steps ("*/15", "1-5", "MON-FRI/2") into the set of allowed values.
+func parseCronField(field string, min, max int, names map[string]int) (map[int]bool, error) {
+	value := func(s string) (int, error) {
+		if n, ok := names[strings.ToUpper(s)]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("invalid value %q, must be between %d and %d", s, min, max)
+		}
+		return n, nil
+	}
+
+	allowed := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = value(bounds[0]); err != nil {
+				return nil, err
+			}
+			if hi, err = value(bounds[1]); err != nil {
+				return nil, err
+			}
+			if lo > hi {
+				return nil, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			n, err := value(rangePart)
+			if err != nil {
+				return nil, err
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+
+		for n := lo; n <= hi; n += step {
+			allowed[n] = true
+		}
+	}
+	return allowed, nil
+}
+
+// onDay reports whether occurrences start on the day of t. As in cron, when
+// both the day of month and the day of week are restricted, a day matching
+// either one matches.
+func (c *cronSchedule) onDay(t time.Time) bool {
+	if !c.month[int(t.Month())] {
+		return false
+	}
+	domMatch, dowMatch := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	if !c.domAny && !c.dowAny {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// Latest implements Recurrence.
+func (c *cronSchedule) Latest(t, after time.Time) (time.Time, bool) {
+	year, month, day := t.Date()
+	for i := 0; ; i++ {
+		date := time.Date(year, month, day-i, 0, 0, 0, 0, t.Location())
+		if c.onDay(date) {
+			for _, hour := range c.hours {
+				for _, minute := range c.minutes {
+					// Starts are tried latest first
+					if start, ok := wallClock(date, hour, minute, t); ok {
+						return start, start.After(after)
+					}
+				}
+			}
+		}
+		if !date.After(after) {
+			return time.Time{}, false
+		}
+	}
+}
+
+// wallClock returns the latest time at or before t at which the clock in t's
+// location shows the hour and minute on the day of date. When the clocks go
+// back there are two such times, and none in the gap when they go forward.
+func wallClock(date time.Time, hour, minute int, t time.Time) (time.Time, bool) {
+	guess := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, t.Location())
+	_, offset := guess.Zone()
+	_, offsetBefore := guess.Add(-3 * time.Hour).Zone()
+	_, offsetAfter := guess.Add(3 * time.Hour).Zone()
+
+	var latest time.Time
+	found := false
+	for _, zoneOffset := range []int{offset, offsetBefore, offsetAfter} {
+		candidate := guess.Add(time.Duration(offset-zoneOffset) * time.Second)
+		if _, o := candidate.Zone(); o != zoneOffset || candidate.Day() != date.Day() ||
+			candidate.Hour() != hour || candidate.Minute() != minute || candidate.After(t) {
+			continue
+		}
+		if !found || candidate.After(latest) {
+			latest, found = candidate, true
+		}
+	}
+	return latest, found
+}
+
+// rrule is the subset of RFC 5545 recurrence rules supported for maintenance
+// windows: DAILY, WEEKLY and MONTHLY frequencies with INTERVAL, BYDAY,
+// BYMONTHDAY and UNTIL. Occurrences start at the time of day of start.
+type rrule struct {
+	freq       string
+	interval   int
+	byDay      map[time.Weekday]bool
+	byMonthDay map[int]bool
+	until      time.Time
+	start      time.Time
+}
+
+var rruleDays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func parseRRule(expr string, start time.Time) (*rrule, error) {
+	r := &rrule{interval: 1, start: start}
+	for _, part := range strings.Split(expr, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY":
+				r.freq = value
+			default:
+				return nil, fmt.Errorf("unsupported RRULE frequency %q, must be DAILY, WEEKLY or MONTHLY", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE interval %q", value)
+			}
+			r.interval = n
+		case "BYDAY":
+			r.byDay = make(map[time.Weekday]bool)
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := rruleDays[day]
+				if !ok {
+					return nil, fmt.Errorf("invalid RRULE day %q", day)
+				}
+				r.byDay[weekday] = true
+			}
+		case "BYMONTHDAY":
+			r.byMonthDay = make(map[int]bool)
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n < 1 || n > 31 {
+					return nil, fmt.Errorf("invalid RRULE month day %q", day)
+				}
+				r.byMonthDay[n] = true
+			}
+		case "UNTIL":
+			until, err := parseRRuleTime(value, start.Location())
+			if err != nil {
+				return nil, err
+			}
+			r.until = until
+		case "WKST":
+			if value != "MO" {
+				return nil, fmt.Errorf("unsupported RRULE week start %q, only MO is supported", value)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part %s", key)
+		}
+	}
+
+	if r.freq == "" {
+		return nil, fmt.Errorf("RRULE must have a FREQ")
+	}
+	if r.byDay != nil && r.freq == "MONTHLY" {
+		return nil, fmt.Errorf("BYDAY is only supported with DAILY and WEEKLY frequencies")
+	}
+	if r.byMonthDay != nil && r.freq != "MONTHLY" {
+		return nil, fmt.Errorf("BYMONTHDAY is only supported with the MONTHLY frequency")
+	}
+	return r, nil
+}
+
+// parseRRuleTime parses an UNTIL value: a UTC date-time, a floating date-time
+// in loc or a date, which includes the whole day.
+func parseRRuleTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102", value, loc); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid RRULE UNTIL %q", value)
+}
+
+// Latest implements Recurrence.
+func (r *rrule) Latest(t, after time.Time) (time.Time, bool) {
+	year, month, day := t.Date()
+	for i := 0; ; i++ {
+		date := time.Date(year, month, day-i, 0, 0, 0, 0, t.Location())
+		if start, ok := wallClock(date, r.start.Hour(), r.start.Minute(), t); ok && r.startsAt(start) {
+			return start, start.After(after)
+		}
+		if !date.After(after) {
+			return time.Time{}, false
+		}
+	}
+}
+
+// startsAt reports whether an occurrence starts at minute t.
+func (r *rrule) startsAt(t time.Time) bool {
+	if t.Hour() != r.start.Hour() || t.Minute() != r.start.Minute() {
+		return false
+	}
+	if t.Before(r.start) || (!r.until.IsZero() && t.After(r.until)) {
+		return false
+	}
+
+	// Whole days between the dates, ignoring the time of day and DST.
+	day := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	days := int(day(t).Sub(day(r.start)).Hours() / 24)
+
+	switch r.freq {
+	case "DAILY":
+		return days%r.interval == 0 && (r.byDay == nil || r.byDay[t.Weekday()])
+	case "WEEKLY":
+		// Weeks start on Monday
+		sinceMonday := (int(r.start.Weekday()) + 6) % 7
+		if (days+sinceMonday)/7%r.interval != 0 {
+			return false
+		}
+		if r.byDay == nil {
+			return t.Weekday() == r.start.Weekday()
+		}
+		return r.byDay[t.Weekday()]
+	case "MONTHLY":
+		months := (t.Year()-r.start.Year())*12 + int(t.Month()-r.start.Month())
+		if months%r.interval != 0 {
+			return false
+		}
+		if r.byMonthDay == nil {
+			return t.Day() == r.start.Day()
+		}
+		return r.byMonthDay[t.Day()]
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package monitoring
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestRecurrenceLatest(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	at := func(s string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", s, newYork)
+		if err != nil {
+			t.Fatalf("invalid time %q: %v", s, err)
+		}
+		return parsed
+	}
+	utc := func(s string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatalf("invalid time %q: %v", s, err)
+		}
+		return parsed.In(newYork)
+	}
+
+	// 2026-03-01 is a Sunday. In New York, clocks go forward from 02:00 to
+	// 03:00 on 2026-03-08 and back from 02:00 to 01:00 on 2026-11-01.
+	tests := []struct {
+		name     string
+		expr     string
+		start    time.Time
+		t, after time.Time
+		want     time.Time // Zero if there is no occurrence
+	}{
+		{"cron same day", "0 2 * * *", at("2026-03-01 00:00"), at("2026-03-04 12:00"), at("2026-03-01 00:00"), at("2026-03-04 02:00")},
+		{"cron previous day", "0 2 * * *", at("2026-03-01 00:00"), at("2026-03-04 01:59"), at("2026-03-01 00:00"), at("2026-03-03 02:00")},
+		{"cron at the start", "0 2 * * *", at("2026-03-01 00:00"), at("2026-03-04 02:00"), at("2026-03-01 00:00"), at("2026-03-04 02:00")},
+		{"cron latest minute", "0,15,30 1-3 * * *", at("2026-03-01 00:00"), at("2026-03-04 02:20"), at("2026-03-01 00:00"), at("2026-03-04 02:15")},
+		{"cron not after after", "0 2 * * *", at("2026-03-01 00:00"), at("2026-03-04 12:00"), at("2026-03-04 02:00"), time.Time{}},
+		{"cron day of week", "0 4 * * SUN", at("2026-03-01 00:00"), at("2026-03-12 12:00"), at("2026-03-01 00:00"), at("2026-03-08 04:00")},
+		{"cron month", "0 0 1 JAN *", at("2026-01-01 00:00"), at("2026-03-12 12:00"), at("2025-06-01 00:00"), at("2026-01-01 00:00")},
+
+		// The day of month and the day of week match either one only if
+		// both are restricted
+		{"cron day of month or week", "0 0 1 * MON", at("2026-03-01 00:00"), at("2026-03-01 12:00"), at("2026-02-01 00:00"), at("2026-03-01 00:00")},
+		{"cron day of week or month", "0 0 1 * MON", at("2026-03-01 00:00"), at("2026-03-05 12:00"), at("2026-02-01 00:00"), at("2026-03-02 00:00")},
+		{"cron every day of month step", "0 0 */1 * MON", at("2026-03-01 00:00"), at("2026-03-05 12:00"), at("2026-02-01 00:00"), at("2026-03-02 00:00")},
+		{"cron every day of month range", "0 0 1-31 * MON", at("2026-03-01 00:00"), at("2026-03-05 12:00"), at("2026-02-01 00:00"), at("2026-03-02 00:00")},
+		{"cron every day of week range", "0 0 15 * 0-6", at("2026-03-01 00:00"), at("2026-03-05 12:00"), at("2026-02-01 00:00"), at("2026-02-15 00:00")},
+		{"cron sunday as 7", "0 0 * * 7", at("2026-03-01 00:00"), at("2026-03-05 12:00"), at("2026-02-01 00:00"), at("2026-03-01 00:00")},
+
+		// 02:30 doesn't exist when clocks go forward
+		{"cron spring forward gap", "30 2 * * *", at("2026-03-01 00:00"), at("2026-03-08 12:00"), at("2026-03-01 00:00"), at("2026-03-07 02:30")},
+		{"cron after spring forward", "30 3 * * *", at("2026-03-01 00:00"), at("2026-03-08 12:00"), at("2026-03-01 00:00"), utc("2026-03-08 07:30")},
+
+		// 01:30 happens twice when clocks go back, first in EDT then in EST
+		{"cron fall back first", "30 1 * * *", at("2026-10-01 00:00"), utc("2026-11-01 05:45"), at("2026-10-01 00:00"), utc("2026-11-01 05:30")},
+		{"cron fall back second", "30 1 * * *", at("2026-10-01 00:00"), utc("2026-11-01 06:45"), at("2026-10-01 00:00"), utc("2026-11-01 06:30")},
+		{"cron fall back between", "30 1 * * *", at("2026-10-01 00:00"), utc("2026-11-01 06:15"), at("2026-10-01 00:00"), utc("2026-11-01 05:30")},
+
+		{"daily", "FREQ=DAILY", at("2026-03-01 09:00"), at("2026-03-04 12:00"), at("2026-03-01 00:00"), at("2026-03-04 09:00")},
+		{"daily before the first", "FREQ=DAILY", at("2026-03-01 09:00"), at("2026-03-01 08:00"), at("2026-02-01 00:00"), time.Time{}},
+		{"daily interval", "FREQ=DAILY;INTERVAL=2", at("2026-03-01 09:00"), at("2026-03-04 12:00"), at("2026-03-01 00:00"), at("2026-03-03 09:00")},
+		{"daily by day", "RRULE:FREQ=DAILY;BYDAY=MO,WE,FR", at("2026-03-01 09:00"), at("2026-03-08 12:00"), at("2026-03-01 00:00"), at("2026-03-06 09:00")},
+		{"daily until date", "FREQ=DAILY;UNTIL=20260305", at("2026-03-01 09:00"), at("2026-03-10 12:00"), at("2026-03-01 00:00"), at("2026-03-05 09:00")},
+		{"daily until time", "FREQ=DAILY;UNTIL=20260305T150000Z", at("2026-03-01 09:00"), at("2026-03-10 12:00"), at("2026-03-01 00:00"), at("2026-03-05 09:00")},
+		{"daily until before time", "FREQ=DAILY;UNTIL=20260305T080000", at("2026-03-01 09:00"), at("2026-03-10 12:00"), at("2026-03-01 00:00"), at("2026-03-04 09:00")},
+		{"daily across spring forward", "FREQ=DAILY", at("2026-03-01 09:00"), at("2026-03-08 12:00"), at("2026-03-01 00:00"), utc("2026-03-08 13:00")},
+		{"daily in spring forward gap", "FREQ=DAILY", at("2026-03-01 02:30"), at("2026-03-08 12:00"), at("2026-03-01 00:00"), at("2026-03-07 02:30")},
+		{"daily fall back", "FREQ=DAILY", at("2026-10-01 01:30"), utc("2026-11-01 06:45"), at("2026-10-01 00:00"), utc("2026-11-01 06:30")},
+
+		{"weekly", "FREQ=WEEKLY", at("2026-03-01 09:00"), at("2026-03-12 12:00"), at("2026-03-01 00:00"), at("2026-03-08 09:00")},
+		{"weekly interval", "FREQ=WEEKLY;INTERVAL=2", at("2026-03-01 09:00"), at("2026-03-12 12:00"), at("2026-03-01 00:00"), at("2026-03-01 09:00")},
+		{"weekly interval by day", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", at("2026-03-02 09:00"), at("2026-03-15 12:00"), at("2026-03-01 00:00"), at("2026-03-04 09:00")},
+		{"weekly interval by day next", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", at("2026-03-02 09:00"), at("2026-03-17 12:00"), at("2026-03-01 00:00"), at("2026-03-16 09:00")},
+		// Weeks start on Monday, so the Sunday start is the end of its week
+		{"weekly interval from sunday", "FREQ=WEEKLY;INTERVAL=2;BYDAY=SA", at("2026-03-01 09:00"), at("2026-03-20 12:00"), at("2026-03-01 00:00"), at("2026-03-14 09:00")},
+
+		{"monthly", "FREQ=MONTHLY", at("2026-01-31 09:00"), at("2026-04-15 12:00"), at("2026-01-01 00:00"), at("2026-03-31 09:00")},
+		{"monthly by month day", "FREQ=MONTHLY;BYMONTHDAY=1,15", at("2026-01-01 09:00"), at("2026-04-10 12:00"), at("2026-01-01 00:00"), at("2026-04-01 09:00")},
+		{"monthly interval", "FREQ=MONTHLY;INTERVAL=3", at("2026-01-10 09:00"), at("2026-06-15 12:00"), at("2026-01-01 00:00"), at("2026-04-10 09:00")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recurrence, err := ParseRecurrence(tt.expr, tt.start)
+			if err != nil {
+				t.Fatalf("ParseRecurrence(%q) failed: %v", tt.expr, err)
+			}
+			got, ok := recurrence.Latest(tt.t, tt.after)
+			if tt.want.IsZero() {
+				if ok {
+					t.Errorf("Latest = %v, want none", got)
+				}
+				return
+			}
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("Latest = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRecurrenceErrors(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for _, expr := range []string{
+		"0 2 * *",
+		"60 2 * * *",
+		"0 24 * * *",
+		"0 2 0 * *",
+		"0 2 * 13 *",
+		"0 2 * * 8",
+		"*/0 2 * * *",
+		"0 5-1 * * *",
+		"0 2 * * FOO",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;BYDAY=XX",
+		"FREQ=MONTHLY;BYDAY=MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;WKST=SU",
+		"FREQ=DAILY;COUNT=3",
+		"INTERVAL=2",
+	} {
+		if _, err := ParseRecurrence(expr, start); err == nil {
+			t.Errorf("ParseRecurrence(%q) succeeded", expr)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package monitoring
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// checkDeadlineMargin is added to the service timeout to bound a whole check,
+// including saving the result and queueing notifications.
+const checkDeadlineMargin = 15 * time.Second
+
+// scheduledCheck is a service in the check queue.
+type scheduledCheck struct {
+	service db.GetServicesAndOwnersRow
+	next    time.Time // When the service is due
+	running bool      // A worker is currently checking the service
+	index   int       // Position in the queue
+}
+
+// checkQueue is a min-heap of scheduled checks ordered by next run time.
+type checkQueue []*scheduledCheck
+
+func (q checkQueue) Len() int           { return len(q) }
+func (q checkQueue) Less(i, j int) bool { return q[i].next.Before(q[j].next) }
+func (q checkQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *checkQueue) Push(x interface{}) {
+	c := x.(*scheduledCheck)
+	c.index = len(*q)
+	*q = append(*q, c)
+}
+
+func (q *checkQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	c := old[n-1]
+	old[n-1] = nil
+	c.index = -1
+	*q = old[:n-1]
+	return c
+}
+
+// interval returns the configured check interval of a service.
+func interval(s db.GetServicesAndOwnersRow) time.Duration {
+	return time.Duration(s.CheckIntervalSeconds) * time.Second
+}
+
+// checkDeadline returns how long a single check of the service may take.
+func checkDeadline(s db.GetServicesAndOwnersRow) time.Duration {
+	return timeoutFor(s) + checkDeadlineMargin
+}
+
+// randomDuration returns a random duration in [0, max).
+func randomDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// syncServices updates the queue with the current service list. New services
+// are spread randomly over their first interval so they don't all fire at once.
+func (m *Monitor) syncServices(services []db.GetServicesAndOwnersRow) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := make(map[int64]bool, len(services))
+	for _, s := range services {
+		seen[s.ID] = true
+
+		if c, ok := m.checks[s.ID]; ok {
+			c.service = s
+			// Pick up a shorter interval without waiting for the old one
+			if latest := now.Add(interval(s)); c.next.After(latest) {
+				c.next = latest
+				heap.Fix(&m.queue, c.index)
+			}
+			continue
+		}
+
+		c := &scheduledCheck{service: s, next: now.Add(randomDuration(interval(s)))}
+		m.checks[s.ID] = c
+		heap.Push(&m.queue, c)
+	}
+
+	for id, c := range m.checks {
+		if !seen[id] {
+			m.removeLocked(id, c)
+		}
+	}
+
+	m.wakeScheduler()
+}
+
+func (m *Monitor) removeLocked(id int64, c *scheduledCheck) {
+	heap.Remove(&m.queue, c.index)
+	delete(m.checks, id)
+}
+
+// dueChecks returns the services that are due and reschedules them for their
+// next interval plus jitter. Services whose previous check is still running
+// are skipped until the next interval.
+func (m *Monitor) dueChecks(now time.Time) []db.GetServicesAndOwnersRow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []db.GetServicesAndOwnersRow
+	for len(m.queue) > 0 && !m.queue[0].next.After(now) {
+		c := m.queue[0]
+		c.next = now.Add(interval(c.service) + randomDuration(m.jitter))
+		heap.Fix(&m.queue, 0)
+
+		if c.running {
+			continue
+		}
+		c.running = true
+		due = append(due, c.service)
+	}
+	return due
+}
+
+// untilNext returns how long to wait until the next service is due.
+func (m *Monitor) untilNext() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.queue) == 0 {
+		return m.refreshInterval
+	}
+	return time.Until(m.queue[0].next)
+}
+
+// finishCheck marks a service as no longer running.
+func (m *Monitor) finishCheck(id int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, ok := m.checks[id]; ok {
+		c.running = false
+	}
+}
+
+// scheduleRetry brings the next check of a service forward to its retry
+// interval, both locally and in the database so any replica can claim it.
+func (m *Monitor) scheduleRetry(ctx context.Context, q *db.Queries, s db.GetServicesAndOwnersRow) error {
+	retryAt := time.Now().Add(time.Duration(s.RetryIntervalSeconds) * time.Second)
+
+	err := q.RescheduleServiceCheck(ctx, db.RescheduleServiceCheckParams{
+		ID:          s.ID,
+		NextCheckAt: pgtype.Timestamptz{Time: retryAt, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("could not reschedule: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.checks[s.ID]
+	if !ok || !retryAt.Before(c.next) {
+		return nil
+	}
+	c.next = retryAt
+	heap.Fix(&m.queue, c.index)
+	m.wakeScheduler()
+	return nil
+}
+
+// wakeScheduler makes the scheduling loop re-evaluate the head of the queue.
+func (m *Monitor) wakeScheduler() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,30 @@
+package monitoring
+
+import (
+	"context"
+	"net"
+	"time"
+	"uptime-monitor/internal/database/db"
+)
+
+// TCPChecker opens a TCP connection to a host:port target.
+// The service is up if the connection is established.
+type TCPChecker struct{}
+
+// Check implements Checker.
+func (c *TCPChecker) Check(ctx context.Context, s db.GetServicesAndOwnersRow) Result {
+	dialer := net.Dialer{
+		Timeout: timeoutFor(s),
+	}
+
+	startTime := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", s.Target)
+	connectTime := time.Since(startTime)
+
+	if err != nil {
+		return Result{Status: StatusDown, ResponseTime: connectTime, Err: err}
+	}
+	conn.Close()
+
+	return Result{Status: StatusUp, ResponseTime: connectTime}
+}
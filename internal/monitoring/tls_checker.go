@@ -0,0 +1,138 @@
+package monitoring
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"net"
+	"time"
+	"uptime-monitor/internal/database/db"
+)
+
+// DefaultCertExpiryThresholds are the days-to-expiry at which a
+// "certificate expiring" alert is sent when a service does not set its own.
+var DefaultCertExpiryThresholds = []int32{30, 14, 7, 1}
+
+// CertInfo describes the leaf certificate presented by a service.
+type CertInfo struct {
+	DaysRemaining int
+	NotAfter      time.Time
+	Issuer        string
+	SANs          []string
+}
+
+// TLSChecker performs a TLS handshake against a host[:port] target (port 443
+// by default) and validates the peer certificate chain and expiry.
+type TLSChecker struct{}
+
+// Check implements Checker.
+func (c *TLSChecker) Check(ctx context.Context, s db.GetServicesAndOwnersRow) Result {
+	address := s.Target
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "443")
+	}
+	host, _, _ := net.SplitHostPort(address)
+
+	// Verification is done by inspectCertificate so that certificate details
+	// are still recorded when the chain is invalid.
+	dialer := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeoutFor(s)},
+		Config:    &tls.Config{ServerName: host, InsecureSkipVerify: true},
+	}
+
+	startTime := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	handshakeTime := time.Since(startTime)
+
+	if err != nil {
+		return Result{Status: StatusDown, ResponseTime: handshakeTime, Err: err}
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	cert, err := inspectCertificate(state.PeerCertificates, host)
+
+	result := Result{Status: StatusUp, ResponseTime: handshakeTime, Cert: cert}
+	if err != nil {
+		result.Status = StatusDown
+		result.Err = err
+	}
+
+	return result
+}
+
+// inspectCertificate extracts the leaf certificate details and verifies the
+// chain against the system roots for the given host name.
+func inspectCertificate(certs []*x509.Certificate, host string) (*CertInfo, error) {
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no peer certificates presented")
+	}
+
+	leaf := certs[0]
+	info := &CertInfo{
+		DaysRemaining: int(math.Floor(time.Until(leaf.NotAfter).Hours() / 24)),
+		NotAfter:      leaf.NotAfter,
+		Issuer:        leaf.Issuer.String(),
+		SANs:          leaf.DNSNames,
+	}
+	for _, ip := range leaf.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	opts := x509.VerifyOptions{
+		DNSName:       host,
+		Intermediates: intermediates,
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		return info, fmt.Errorf("certificate verification failed: %w", err)
+	}
+
+	return info, nil
+}
+
+// crossedThreshold returns the smallest threshold that the certificate has
+// reached since the previous check, or false if no new threshold was reached.
+// previousDays is nil when no certificate was recorded before.
+func crossedThreshold(thresholds []int32, previousDays *int, days int) (int, bool) {
+	crossed, found := 0, false
+	for _, t := range thresholds {
+		threshold := int(t)
+		if days > threshold {
+			continue
+		}
+		if previousDays != nil && *previousDays <= threshold {
+			continue // Already alerted for this threshold
+		}
+		if !found || threshold < crossed {
+			crossed, found = threshold, true
+		}
+	}
+	return crossed, found
+}
+
+// certificateRenewed reports whether the certificate had reached one of the
+// thresholds at the previous check but no longer reaches any, which means it
+// was renewed.
+func certificateRenewed(thresholds []int32, previousDays *int, days int) bool {
+	if previousDays == nil {
+		return false
+	}
+	alerted := false
+	for _, t := range thresholds {
+		threshold := int(t)
+		if days <= threshold {
+			return false
+		}
+		if *previousDays <= threshold {
+			alerted = true
+		}
+	}
+	return alerted
+}
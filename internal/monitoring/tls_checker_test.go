@@ -0,0 +1,39 @@
+package monitoring
+
+import "testing"
+
+func TestCertificateThresholds(t *testing.T) {
+	days := func(n int) *int { return &n }
+	thresholds := []int32{30, 14, 7, 1}
+
+	tests := []struct {
+		name          string
+		previousDays  *int
+		days          int
+		wantThreshold int
+		wantCrossed   bool
+		wantRenewed   bool
+	}{
+		{"first check far from expiry", nil, 90, 0, false, false},
+		{"first check within a threshold", nil, 10, 14, true, false},
+		{"crossing a threshold", days(31), 30, 30, true, false},
+		{"crossing several thresholds", days(31), 5, 7, true, false},
+		{"already alerted", days(13), 12, 0, false, false},
+		{"expired", days(2), -1, 1, true, false},
+		{"renewed", days(12), 89, 0, false, true},
+		{"renewed after expiry", days(-3), 89, 0, false, true},
+		{"not alerted before", days(45), 89, 0, false, false},
+		{"renewed but still within a threshold", days(5), 20, 0, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			threshold, crossed := crossedThreshold(thresholds, tt.previousDays, tt.days)
+			if threshold != tt.wantThreshold || crossed != tt.wantCrossed {
+				t.Errorf("crossedThreshold = %d, %v, want %d, %v", threshold, crossed, tt.wantThreshold, tt.wantCrossed)
+			}
+			if renewed := certificateRenewed(thresholds, tt.previousDays, tt.days); renewed != tt.wantRenewed {
+				t.Errorf("certificateRenewed = %v, want %v", renewed, tt.wantRenewed)
+			}
+		})
+	}
+}
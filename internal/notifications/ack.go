@@ -0,0 +1,41 @@
+package notifications
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// SignAckLink returns the signature of a link that acknowledges the incident
+// on behalf of email until expires. The secret is the server's JWT secret;
+// the "incident-ack" prefix keeps the signature from being valid elsewhere.
+func SignAckLink(secret string, incidentID int64, email string, expires time.Time) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "incident-ack:%d:%s:%d", incidentID, email, expires.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyAckLink checks the signature and expiry of an acknowledgement link.
+func VerifyAckLink(secret string, incidentID int64, email string, expires time.Time, signature string) bool {
+	if time.Now().After(expires) {
+		return false
+	}
+	expected := SignAckLink(secret, incidentID, email, expires)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// AckURL returns a signed one-click link acknowledging the incident, sent to
+// the given email address.
+func AckURL(baseURL, secret string, incidentID int64, email string, ttl time.Duration) string {
+	expires := time.Now().Add(ttl)
+	query := url.Values{
+		"email":   {email},
+		"expires": {strconv.FormatInt(expires.Unix(), 10)},
+		"sig":     {SignAckLink(secret, incidentID, email, expires)},
+	}
+	return fmt.Sprintf("%s/incidents/%d/ack?%s", baseURL, incidentID, query.Encode())
+}
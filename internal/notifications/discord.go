@@ -0,0 +1,51 @@
+package notifications
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"uptime-monitor/internal/database/db"
+)
+
+// DiscordNotifier posts alerts to Discord webhooks as embeds.
+type DiscordNotifier struct {
+	client *http.Client
+}
+
+// NewDiscordNotifier creates a new Discord notifier.
+func NewDiscordNotifier() *DiscordNotifier {
+	return &DiscordNotifier{client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// Send implements Notifier.
+func (n *DiscordNotifier) Send(ctx context.Context, channel db.NotificationChannel, alert Alert) error {
+	return postJSON(ctx, n.client, channel.Target, discordPayload(alert))
+}
+
+func discordPayload(alert Alert) map[string]interface{} {
+	var fields []map[string]interface{}
+	for _, f := range alert.Fields() {
+		fields = append(fields, map[string]interface{}{
+			"name":   f.Name,
+			"value":  f.Value,
+			"inline": f.Name != "Error" && f.Name != "Target",
+		})
+	}
+
+	// Embed colors are integers
+	color, _ := strconv.ParseInt(strings.TrimPrefix(statusColor(alert.Status), "#"), 16, 32)
+
+	return map[string]interface{}{
+		"embeds": []map[string]interface{}{
+			{
+				"title":       alert.Subject,
+				"description": alert.Message,
+				"color":       color,
+				"fields":      fields,
+				"timestamp":   alert.Time.UTC().Format(time.RFC3339),
+			},
+		},
+	}
+}
@@ -0,0 +1,23 @@
+package notifications
+
+import "testing"
+
+func TestDiscordPayload(t *testing.T) {
+	got := sendToServer(t, NewDiscordNotifier(), testAlert)
+	assertJSON(t, got, `{
+		"embeds": [{
+			"title": "Service Alert: API is DOWN",
+			"description": "The service 'API' is down.",
+			"color": 14687834,
+			"fields": [
+				{"name": "Service", "value": "API", "inline": true},
+				{"name": "Target", "value": "https://api.example.com/health", "inline": false},
+				{"name": "Status", "value": "down", "inline": true},
+				{"name": "Status code", "value": "503", "inline": true},
+				{"name": "Latency", "value": "1234ms", "inline": true},
+				{"name": "Error", "value": "unexpected status code 503", "inline": false}
+			],
+			"timestamp": "2026-03-01T12:30:45Z"
+		}]
+	}`)
+}
@@ -0,0 +1,235 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"uptime-monitor/internal/config"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Outbox statuses stored in notification_outbox.status.
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxFailed    = "failed"
+)
+
+const (
+	// dispatchLease bounds a single delivery. Expired leases are picked up
+	// again by any dispatcher.
+	dispatchLease = 5 * time.Minute
+
+	// Failed deliveries are retried after 30s, 1m, 2m, ... up to an hour.
+	dispatchInitialBackoff = 30 * time.Second
+	dispatchMaxBackoff     = time.Hour
+)
+
+// Dispatcher delivers the notifications queued in the outbox. Failed
+// deliveries are retried with exponential backoff until they run out of
+// attempts, at which point they are dead-lettered for manual re-sending.
+type Dispatcher struct {
+	q          *db.Queries
+	notifiers  map[string]Notifier
+	instanceID string
+
+	concurrency  int
+	pollInterval time.Duration
+
+	deliveries sync.WaitGroup
+	stopped    chan struct{}
+}
+
+// NewDispatcher creates a dispatcher with a notifier for every channel type.
+func NewDispatcher(cfg *config.Config, q *db.Queries) *Dispatcher {
+	d := &Dispatcher{
+		q:            q,
+		notifiers:    NewNotifiers(cfg, q),
+		instanceID:   cfg.InstanceID,
+		concurrency:  cfg.NotifyConcurrency,
+		pollInterval: cfg.NotifyPollInterval,
+		stopped:      make(chan struct{}),
+	}
+	if d.concurrency <= 0 {
+		d.concurrency = 1
+	}
+	if d.pollInterval <= 0 {
+		d.pollInterval = 2 * time.Second
+	}
+	return d
+}
+
+// Start delivers due notifications until ctx is cancelled. Deliveries already
+// running are left to Shutdown.
+func (d *Dispatcher) Start(ctx context.Context) {
+	defer close(d.stopped)
+	log.Printf("Notification dispatcher started with %d workers", d.concurrency)
+
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	sem := make(chan struct{}, d.concurrency)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Notification dispatcher stopped claiming notifications")
+			return
+		case <-ticker.C:
+		}
+
+		free := d.concurrency - len(sem)
+		if free == 0 {
+			continue
+		}
+		batch, err := d.q.ClaimOutboxNotifications(ctx, db.ClaimOutboxNotificationsParams{
+			LeaseSeconds: int32(dispatchLease.Seconds()),
+			InstanceID:   d.instanceID,
+			BatchSize:    int32(free),
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("ERROR: Could not claim notifications: %v", err)
+			}
+			continue
+		}
+
+		for _, n := range batch {
+			sem <- struct{}{}
+			d.deliveries.Add(1)
+			go func(n db.NotificationOutbox) {
+				defer d.deliveries.Done()
+				defer func() { <-sem }()
+				d.deliver(n)
+			}(n)
+		}
+	}
+}
+
+// Shutdown waits for the dispatcher to stop and for in-flight deliveries to
+// finish, or for ctx to expire. Unfinished deliveries are retried by the next
+// dispatcher once their lease expires.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		<-d.stopped
+		d.deliveries.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("Notification dispatcher finished in-flight deliveries")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliver sends one notification and records the outcome. It runs to
+// completion on shutdown so that the outcome is not lost.
+func (d *Dispatcher) deliver(n db.NotificationOutbox) {
+	ctx, cancel := context.WithTimeout(context.Background(), dispatchLease)
+	defer cancel()
+
+	err := d.send(ctx, n)
+	if err == nil {
+		if err := d.q.MarkNotificationDelivered(ctx, n.ID); err != nil {
+			log.Printf("ERROR: Could not mark notification %d as delivered: %v", n.ID, err)
+		}
+		return
+	}
+
+	about := "" // Notifications to status page subscribers may have no service
+	if n.ServiceID.Valid {
+		about = fmt.Sprintf(" for service %d", n.ServiceID.Int64)
+	}
+	attempts := int(n.Attempts) + 1
+	if attempts >= int(n.MaxAttempts) {
+		log.Printf("ERROR: Notification %d%s failed after %d attempts, dead-lettered: %v", n.ID, about, attempts, err)
+	} else {
+		log.Printf("ERROR: Notification %d%s failed (attempt %d/%d): %v", n.ID, about, attempts, n.MaxAttempts, err)
+	}
+
+	err = d.q.MarkNotificationFailed(ctx, db.MarkNotificationFailedParams{
+		NextAttemptAt: pgtype.Timestamptz{Time: time.Now().Add(retryBackoff(attempts)), Valid: true},
+		LastError:     pgtype.Text{String: err.Error(), Valid: true},
+		ID:            n.ID,
+	})
+	if err != nil {
+		log.Printf("ERROR: Could not record failure of notification %d: %v", n.ID, err)
+	}
+}
+
+// send delivers the notification through the notifier for its channel type.
+func (d *Dispatcher) send(ctx context.Context, n db.NotificationOutbox) error {
+	var alert Alert
+	if err := json.Unmarshal(n.Alert, &alert); err != nil {
+		return fmt.Errorf("invalid alert: %w", err)
+	}
+	alert.NotificationID = n.ID
+	alert.Attempt = int(n.Attempts) + 1
+
+	channel := db.NotificationChannel{Type: n.ChannelType, Target: n.Target}
+	if n.ChannelID.Valid {
+		var err error
+		if channel, err = d.q.GetNotificationChannel(ctx, n.ChannelID.Int64); err != nil {
+			return fmt.Errorf("could not load channel %d: %w", n.ChannelID.Int64, err)
+		}
+	}
+
+	notifier, ok := d.notifiers[channel.Type]
+	if !ok {
+		return fmt.Errorf("unknown notification channel type %q", channel.Type)
+	}
+	return notifier.Send(ctx, channel, alert)
+}
+
+// retryBackoff returns how long to wait before the next attempt after the given number of failures.
+func retryBackoff(failures int) time.Duration {
+	backoff := dispatchInitialBackoff
+	for i := 1; i < failures && backoff < dispatchMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > dispatchMaxBackoff {
+		backoff = dispatchMaxBackoff
+	}
+	return backoff
+}
+
+// Enqueue queues the alert for delivery to each channel with q, which may be
+// bound to the transaction that recorded the event. With no channels, the
+// alert is sent to the fallback channel, such as the owner's email address.
+func Enqueue(ctx context.Context, q *db.Queries, channels []db.NotificationChannel, fallback db.NotificationChannel, alert Alert, maxAttempts int) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	if len(channels) == 0 {
+		channels = []db.NotificationChannel{fallback}
+	}
+	for _, channel := range channels {
+		params := db.EnqueueNotificationParams{
+			ChannelType: channel.Type,
+			Target:      channel.Target,
+			Alert:       payload,
+			MaxAttempts: int32(maxAttempts),
+		}
+		if alert.ServiceID != 0 {
+			params.ServiceID = pgtype.Int8{Int64: alert.ServiceID, Valid: true}
+		}
+		if channel.ID != 0 {
+			params.ChannelID = pgtype.Int8{Int64: channel.ID, Valid: true}
+			params.Target = "" // Read from the channel at delivery, so it's not copied
+		}
+		if err := q.EnqueueNotification(ctx, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,38 +1,173 @@
 package notifications
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"net/smtp"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
 	"uptime-monitor/internal/config"
+	"uptime-monitor/internal/database/db"
 )
 
 // EmailNotifier handles sending emails.
 type EmailNotifier struct {
-	cfg *config.Config
+	cfg       *config.Config
+	q         *db.Queries
+	templates *emailTemplates
 }
 
-// NewEmailNotifier creates a new notifier.
-func NewEmailNotifier(cfg *config.Config) *EmailNotifier {
-	return &EmailNotifier{cfg: cfg}
+// NewEmailNotifier creates a new notifier. If the templates directory has an
+// invalid template, the error is logged and the built-in templates are used.
+// Alerts about a service are rendered with the templates its owner
+// overrode, if any.
+func NewEmailNotifier(cfg *config.Config, q *db.Queries) *EmailNotifier {
+	templates, err := loadEmailTemplates(cfg.EmailTemplatesDir)
+	if err != nil {
+		log.Printf("ERROR: Could not load email templates from %s, using the defaults: %v", cfg.EmailTemplatesDir, err)
+		if templates, err = loadEmailTemplates(""); err != nil {
+			panic(fmt.Sprintf("built-in email templates are invalid: %v", err))
+		}
+	}
+	return &EmailNotifier{cfg: cfg, q: q, templates: templates}
 }
 
-// SendNotification sends an email to a recipient.
-func (n *EmailNotifier) SendNotification(to, subject, body string) error {
+// Send implements Notifier by emailing the alert to the recipient as a
+// multipart message with plain text and HTML versions.
+func (n *EmailNotifier) Send(ctx context.Context, channel db.NotificationChannel, alert Alert) error {
 	// Check if SMTP is configured
-	if n.cfg.SMTPHost == "" || n.cfg.SMTPPort == "" || n.cfg.SMTPUsername == "" || n.cfg.SMTPPassword == "" {
+	if n.cfg.SMTPHost == "" || n.cfg.SMTPPort == "" {
 		return fmt.Errorf("SMTP not configured. Skipping email notification")
 	}
+	if n.cfg.SMTPAuth != "none" && (n.cfg.SMTPUsername == "" || n.cfg.SMTPPassword == "") {
+		return fmt.Errorf("SMTP credentials not configured. Skipping email notification")
+	}
+
+	from, err := mail.ParseAddress(n.cfg.EmailSender)
+	if err != nil {
+		return fmt.Errorf("invalid EMAIL_SENDER: %w", err)
+	}
+	to, err := mail.ParseAddress(channel.Target)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+
+	// Each recipient gets their own link, so the timeline shows who acknowledged
+	var ackURL string
+	if alert.IncidentID != 0 && n.cfg.PublicURL != "" {
+		ackURL = AckURL(n.cfg.PublicURL, n.cfg.JWTSecret, alert.IncidentID, to.Address, n.cfg.AckLinkTTL)
+	}
+
+	subject, text, html, err := n.templatesFor(ctx, alert.ServiceID).render(alert, ackURL)
+	if err != nil {
+		// A user's template that fails on this alert doesn't lose it
+		log.Printf("ERROR: Could not render email for service %d with its owner's templates, using the defaults: %v", alert.ServiceID, err)
+		if subject, text, html, err = n.templates.render(alert, ackURL); err != nil {
+			return fmt.Errorf("could not render email: %w", err)
+		}
+	}
+
+	msg, err := buildMessage(from, to, subject, text, html)
+	if err != nil {
+		return err
+	}
+	return sendMail(ctx, n.cfg, from.Address, []string{to.Address}, msg)
+}
+
+// templatesFor returns the templates of the owner of the service, which fall
+// back to the defaults for those they haven't overridden. The defaults are
+// used as a whole if the overrides can't be loaded.
+func (n *EmailNotifier) templatesFor(ctx context.Context, serviceID int64) *emailTemplates {
+	if serviceID == 0 {
+		return n.templates
+	}
+	rows, err := n.q.GetEmailTemplatesForService(ctx, serviceID)
+	if err != nil {
+		log.Printf("ERROR: Could not get email templates for service %d, using the defaults: %v", serviceID, err)
+		return n.templates
+	}
+	if len(rows) == 0 {
+		return n.templates
+	}
 
-	auth := smtp.PlainAuth("", n.cfg.SMTPUsername, n.cfg.SMTPPassword, n.cfg.SMTPHost)
+	overrides := make(map[string]string, len(rows))
+	for _, row := range rows {
+		overrides[row.Name] = row.Content
+	}
+	templates, err := n.templates.withOverrides(overrides)
+	if err != nil {
+		log.Printf("ERROR: Could not parse email templates for service %d, using the defaults: %v", serviceID, err)
+		return n.templates
+	}
+	return templates
+}
 
-	// Construct the email message
-	msg := []byte(fmt.Sprintf("To: %s\r\n"+
-		"From: %s\r\n"+
-		"Subject: %s\r\n"+
-		"\r\n"+
-		"%s\r\n", to, n.cfg.EmailSender, subject, body))
+// buildMessage assembles a multipart/alternative MIME message. The subject
+// and display names are RFC 2047 encoded and both parts are quoted-printable,
+// so non-ASCII text and long lines survive any relay.
+func buildMessage(from, to *mail.Address, subject, text, html string) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := writePart(mw, "text/plain; charset=UTF-8", text); err != nil {
+		return nil, err
+	}
+	if err := writePart(mw, "text/html; charset=UTF-8", html); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
 
-	addr := fmt.Sprintf("%s:%s", n.cfg.SMTPHost, n.cfg.SMTPPort)
+	var msg bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&msg, "%s: %s\r\n", key, value)
+	}
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("UTF-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", newMessageID(from.Address))
+	header("MIME-Version", "1.0")
+	header("Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", mw.Boundary()))
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
 
-	return smtp.SendMail(addr, auth, n.cfg.EmailSender, []string{to}, msg)
+// writePart adds a quoted-printable part with CRLF line endings.
+func writePart(mw *multipart.Writer, contentType, content string) error {
+	w, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(w)
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if _, err := qp.Write([]byte(strings.ReplaceAll(content, "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// newMessageID returns a unique Message-ID in the sender's domain.
+func newMessageID(sender string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(sender, "@"); at >= 0 {
+		domain = sender[at+1:]
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("<%d@%s>", time.Now().UnixNano(), domain)
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
 }
@@ -0,0 +1,166 @@
+package notifications
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Built-in alert email templates, each of which can be overridden by a file
+// of the same name in the configured templates directory, and then by each
+// user for their own alerts.
+//
+//go:embed templates/*.tmpl
+var defaultEmailTemplates embed.FS
+
+const (
+	subjectTemplate  = "subject.tmpl"
+	textBodyTemplate = "body.txt.tmpl"
+	htmlBodyTemplate = "body.html.tmpl"
+)
+
+// EmailTemplateNames are the names of the templates that can be overridden.
+var EmailTemplateNames = []string{subjectTemplate, textBodyTemplate, htmlBodyTemplate}
+
+// emailTemplates renders the subject and the plain text and HTML bodies of
+// alert emails.
+type emailTemplates struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// emailData is what the templates are executed with: the alert's fields and
+// Fields method, plus a few presentation helpers.
+type emailData struct {
+	Alert
+	Color         string // Hex color for the alert status
+	FormattedTime string
+	AckURL        string // Signed link acknowledging IncidentID, if any
+}
+
+// loadEmailTemplates parses the built-in templates, replacing each with its
+// override from dir if dir is set and contains one.
+func loadEmailTemplates(dir string) (*emailTemplates, error) {
+	read := func(name string) (string, error) {
+		if dir != "" {
+			b, err := os.ReadFile(filepath.Join(dir, name))
+			if err == nil {
+				return string(b), nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+		}
+		b, err := defaultEmailTemplates.ReadFile("templates/" + name)
+		return string(b), err
+	}
+
+	sources := make(map[string]string, len(EmailTemplateNames))
+	for _, name := range EmailTemplateNames {
+		src, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		sources[name] = src
+	}
+	return (&emailTemplates{}).withOverrides(sources)
+}
+
+// withOverrides returns a copy of the templates in which those named in
+// overrides are parsed from their source.
+func (t *emailTemplates) withOverrides(overrides map[string]string) (*emailTemplates, error) {
+	o := *t
+	var err error
+	if src, ok := overrides[subjectTemplate]; ok {
+		if o.subject, err = texttemplate.New(subjectTemplate).Parse(src); err != nil {
+			return nil, err
+		}
+	}
+	if src, ok := overrides[textBodyTemplate]; ok {
+		if o.text, err = texttemplate.New(textBodyTemplate).Parse(src); err != nil {
+			return nil, err
+		}
+	}
+	if src, ok := overrides[htmlBodyTemplate]; ok {
+		if o.html, err = htmltemplate.New(htmlBodyTemplate).Parse(src); err != nil {
+			return nil, err
+		}
+	}
+	return &o, nil
+}
+
+// sampleAlert is what templates are rendered with when they are validated.
+var sampleAlert = Alert{
+	Event:          EventServiceDown,
+	ServiceID:      1,
+	ServiceName:    "Example",
+	Target:         "https://example.com",
+	Status:         "down",
+	PreviousStatus: "up",
+	StatusCode:     503,
+	ResponseTime:   1500 * time.Millisecond,
+	Error:          "unexpected status code 503",
+	Subject:        "Service Alert: Example is DOWN",
+	Message:        "The service 'Example' (https://example.com) is down.",
+	IncidentID:     1,
+}
+
+// ValidateEmailTemplate parses the source of the named template and renders
+// it with a sample alert, so that mistakes are reported when a user saves it
+// rather than when their alerts are sent.
+func ValidateEmailTemplate(name, src string) error {
+	if !slices.Contains(EmailTemplateNames, name) {
+		return fmt.Errorf("unknown template %q, must be one of %s", name, strings.Join(EmailTemplateNames, ", "))
+	}
+	defaults, err := loadEmailTemplates("")
+	if err != nil {
+		return err
+	}
+	t, err := defaults.withOverrides(map[string]string{name: src})
+	if err != nil {
+		return err
+	}
+	alert := sampleAlert
+	alert.Time = time.Now()
+	_, _, _, err = t.render(alert, "https://example.com/incidents/1/ack")
+	return err
+}
+
+// render executes the templates for the alert. The subject is collapsed onto
+// one line, since templates usually end with a newline.
+func (t *emailTemplates) render(alert Alert, ackURL string) (subject, text, html string, err error) {
+	data := emailData{
+		Alert:         alert,
+		Color:         statusColor(alert.Status),
+		FormattedTime: alert.Time.UTC().Format(time.RFC1123),
+		AckURL:        ackURL,
+	}
+
+	var buf bytes.Buffer
+	if err = t.subject.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+	subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	buf.Reset()
+	if err = t.text.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+	text = buf.String()
+
+	buf.Reset()
+	if err = t.html.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+	return subject, text, buf.String(), nil
+}
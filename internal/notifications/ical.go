@@ -0,0 +1,68 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const icalTimeFormat = "20060102T150405Z"
+
+// ICalendar renders the shifts of an on-call schedule as an iCalendar
+// (RFC 5545) feed with one event per shift. Times are in UTC, so calendar
+// apps show them in the viewer's time zone.
+func ICalendar(scheduleID int64, name string, shifts []Shift) []byte {
+	var buf bytes.Buffer
+	line := func(format string, args ...any) {
+		buf.WriteString(foldICalLine(fmt.Sprintf(format, args...)))
+		buf.WriteString("\r\n")
+	}
+
+	now := time.Now().UTC().Format(icalTimeFormat)
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//uptime-monitor//On-call schedule//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:%s", escapeICalText(name))
+	for _, shift := range shifts {
+		summary := "On call: " + shift.Email
+		if shift.Override {
+			summary += " (override)"
+		}
+		line("BEGIN:VEVENT")
+		line("UID:oncall-%d-%d@uptime-monitor", scheduleID, shift.Start.Unix())
+		line("DTSTAMP:%s", now)
+		line("DTSTART:%s", shift.Start.UTC().Format(icalTimeFormat))
+		line("DTEND:%s", shift.End.UTC().Format(icalTimeFormat))
+		line("SUMMARY:%s", escapeICalText(summary))
+		line("DESCRIPTION:%s", escapeICalText(fmt.Sprintf("%s is on call for %s.", shift.Email, name)))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// escapeICalText escapes a TEXT property value.
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICalLine splits a content line into lines of at most 75 octets, each
+// continuation starting with a space. UTF-8 sequences are not split.
+func foldICalLine(s string) string {
+	const limit = 75
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
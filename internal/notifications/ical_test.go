@@ -0,0 +1,57 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEscapeICalText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Primary on-call", "Primary on-call"},
+		{"a,b;c", `a\,b\;c`},
+		{`C:\ops`, `C:\\ops`},
+		{"line one\nline two", `line one\nline two`},
+		{"line one\r\nline two", `line one\nline two`},
+		{"Équipe: été", "Équipe: été"},
+	}
+	for _, tt := range tests {
+		if got := escapeICalText(tt.in); got != tt.want {
+			t.Errorf("escapeICalText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFoldICalLine(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"short", "SUMMARY:On call", "SUMMARY:On call"},
+		{"75 octets", strings.Repeat("a", 75), strings.Repeat("a", 75)},
+		{"76 octets", strings.Repeat("a", 76), strings.Repeat("a", 75) + "\r\n a"},
+		{"continuations", strings.Repeat("a", 150), strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 74) + "\r\n a"},
+		// A 2-octet character that would end at octet 76 starts the next line
+		{"two-octet characters", "SUMMARY:" + strings.Repeat("é", 40),
+			"SUMMARY:" + strings.Repeat("é", 33) + "\r\n " + strings.Repeat("é", 7)},
+		{"three-octet characters", strings.Repeat("€", 30),
+			strings.Repeat("€", 25) + "\r\n " + strings.Repeat("€", 5)},
+		{"four-octet characters", strings.Repeat("😀", 20),
+			strings.Repeat("😀", 18) + "\r\n " + strings.Repeat("😀", 2)},
+	}
+	for _, tt := range tests {
+		got := foldICalLine(tt.in)
+		if got != tt.want {
+			t.Errorf("%s: foldICalLine = %q, want %q", tt.name, got, tt.want)
+		}
+		for _, line := range strings.Split(got, "\r\n") {
+			if len(line) > 75 || !utf8.ValidString(line) {
+				t.Errorf("%s: folded line %q is %d octets or splits a character", tt.name, line, len(line))
+			}
+		}
+		if unfolded := strings.ReplaceAll(got, "\r\n ", ""); unfolded != tt.in {
+			t.Errorf("%s: unfolded line = %q, want %q", tt.name, unfolded, tt.in)
+		}
+	}
+}
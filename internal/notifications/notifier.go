@@ -0,0 +1,128 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+	"uptime-monitor/internal/config"
+	"uptime-monitor/internal/database/db"
+)
+
+// Channel types stored in notification_channels.type.
+const (
+	ChannelEmail      = "email"
+	ChannelSlack      = "slack"
+	ChannelDiscord    = "discord"
+	ChannelTeams      = "teams"
+	ChannelWebhook    = "webhook"
+	ChannelPagerDuty  = "pagerduty"
+	ChannelOpsgenie   = "opsgenie"
+	ChannelOnCall     = "oncall"     // Target is an on-call schedule ID
+	ChannelSubscriber = "subscriber" // Outbox only: target is a status page subscriber ID
+)
+
+// Event types sent to webhook channels.
+const (
+	EventServiceDown         = "service.down"
+	EventServiceDegraded     = "service.degraded"
+	EventServiceUp           = "service.up"
+	EventServiceFlapping     = "service.flapping"   // Changes state too often; per-change alerts are suppressed
+	EventServiceStabilized   = "service.stabilized" // Stopped flapping; Status is its confirmed status
+	EventIncidentResolved    = "incident.resolved"
+	EventIncidentEscalated   = "incident.escalated" // Still open after an escalation policy delay
+	EventCertificateExpiring = "certificate.expiring"
+	EventCertificateRenewed  = "certificate.renewed"  // No longer within any expiry threshold after an expiring alert
+	EventIncidentUpdated     = "incident.updated"     // Update posted for status page subscribers
+	EventSubscriptionConfirm = "subscription.confirm" // Asks a status page subscriber to confirm their email or webhook
+)
+
+// Alert is a notification about a service, rendered by each channel in its
+// own format.
+type Alert struct {
+	Event          string        `json:"event"` // One of the Event constants
+	ServiceID      int64         `json:"service_id"`
+	ServiceName    string        `json:"service_name"`
+	Target         string        `json:"target"`
+	Status         string        `json:"status,omitempty"` // New status for state changes, empty for other alerts
+	PreviousStatus string        `json:"previous_status,omitempty"`
+	StatusCode     int           `json:"status_code,omitempty"`
+	ResponseTime   time.Duration `json:"response_time,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	Subject        string        `json:"subject"` // One-line summary, e.g. the email subject
+	Message        string        `json:"message"` // Plain text description
+	Time           time.Time     `json:"time"`
+
+	// Set on alerts about an open incident, which emails link to acknowledge
+	IncidentID      int64 `json:"incident_id,omitempty"`
+	EscalationLevel int   `json:"escalation_level,omitempty"` // 1 for the first level
+
+	// Set on recovery alerts when the service's incident was resolved
+	ResolvedIncidentID int64         `json:"resolved_incident_id,omitempty"`
+	IncidentDuration   time.Duration `json:"incident_duration,omitempty"`
+
+	// Set on certificate alerts
+	CertDaysRemaining int       `json:"cert_days_remaining,omitempty"`
+	CertExpiresAt     time.Time `json:"cert_expires_at,omitempty"`
+
+	// Set on alerts to status page subscribers when they are delivered
+	ConfirmURL     string `json:"confirm_url,omitempty"`
+	UnsubscribeURL string `json:"unsubscribe_url,omitempty"`
+
+	// Set by the dispatcher on alerts delivered from the outbox
+	NotificationID int64 `json:"-"`
+	Attempt        int   `json:"-"` // 1 for the first delivery
+}
+
+// Field is a labelled value shown in rich chat messages.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields returns the details of the alert that are set.
+func (a Alert) Fields() []Field {
+	var fields []Field
+	if a.ServiceName != "" {
+		fields = append(fields, Field{Name: "Service", Value: a.ServiceName})
+	}
+	if a.Target != "" {
+		fields = append(fields, Field{Name: "Target", Value: a.Target})
+	}
+	if a.Status != "" {
+		fields = append(fields, Field{Name: "Status", Value: a.Status})
+	}
+	if a.StatusCode != 0 {
+		fields = append(fields, Field{Name: "Status code", Value: strconv.Itoa(a.StatusCode)})
+	}
+	if a.ResponseTime > 0 {
+		fields = append(fields, Field{Name: "Latency", Value: fmt.Sprintf("%dms", a.ResponseTime.Milliseconds())})
+	}
+	if a.Error != "" {
+		fields = append(fields, Field{Name: "Error", Value: a.Error})
+	}
+	return fields
+}
+
+// Notifier delivers alerts to one kind of channel, at the channel's target
+// address such as an email address or a webhook URL.
+type Notifier interface {
+	Send(ctx context.Context, channel db.NotificationChannel, alert Alert) error
+}
+
+// NewNotifiers returns a Notifier for every channel type.
+func NewNotifiers(cfg *config.Config, q *db.Queries) map[string]Notifier {
+	email := NewEmailNotifier(cfg, q)
+	webhook := NewWebhookNotifier(q)
+	return map[string]Notifier{
+		ChannelEmail:      email,
+		ChannelSlack:      NewSlackNotifier(),
+		ChannelDiscord:    NewDiscordNotifier(),
+		ChannelTeams:      NewTeamsNotifier(),
+		ChannelWebhook:    webhook,
+		ChannelPagerDuty:  NewPagerDutyNotifier(q, cfg.PagerDutyEventsURL),
+		ChannelOpsgenie:   NewOpsgenieNotifier(q, cfg.OpsgenieAPIURL),
+		ChannelOnCall:     NewOnCallNotifier(q, email),
+		ChannelSubscriber: NewSubscriberNotifier(q, cfg.PublicURL, email, NewPublicWebhookNotifier(q)),
+	}
+}
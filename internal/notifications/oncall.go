@@ -0,0 +1,258 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Rotations of on-call schedules: who is on call changes every day or every
+// week at the handoff time.
+const (
+	RotationDaily  = "daily"
+	RotationWeekly = "weekly"
+)
+
+// Shift is a period during which one person is on call.
+type Shift struct {
+	Email    string    `json:"email"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Override bool      `json:"override"` // Taken over from the rotation
+}
+
+// Schedule works out who is on call from an on-call schedule's rotation and
+// overrides. Handoffs happen at the same local time in the schedule's time
+// zone, whatever the UTC offset that day.
+type Schedule struct {
+	rotation  string
+	members   []string
+	loc       *time.Location
+	startsOn  time.Time     // Midnight UTC of the first shift's local date
+	handoff   time.Duration // After local midnight
+	overrides []db.OncallOverride
+}
+
+// NewSchedule returns the schedule with the given overrides, oldest first.
+// Later overrides take precedence where they overlap.
+func NewSchedule(schedule db.OncallSchedule, overrides []db.OncallOverride) (*Schedule, error) {
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", schedule.Timezone)
+	}
+	if schedule.Rotation != RotationDaily && schedule.Rotation != RotationWeekly {
+		return nil, fmt.Errorf("unknown rotation %q", schedule.Rotation)
+	}
+	d := schedule.StartsOn.Time
+	return &Schedule{
+		rotation:  schedule.Rotation,
+		members:   schedule.Members,
+		loc:       loc,
+		startsOn:  time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC),
+		handoff:   time.Duration(schedule.HandoffTime.Microseconds) * time.Microsecond,
+		overrides: overrides,
+	}, nil
+}
+
+// handoffAt returns when the rotation's shift number k starts; shift 0 is the
+// first member's first shift.
+func (s *Schedule) handoffAt(k int) time.Time {
+	days := k
+	if s.rotation == RotationWeekly {
+		days *= 7
+	}
+	h := int(s.handoff / time.Hour)
+	m := int(s.handoff % time.Hour / time.Minute)
+	return time.Date(s.startsOn.Year(), s.startsOn.Month(), s.startsOn.Day()+days, h, m, 0, 0, s.loc)
+}
+
+// shiftIndex returns the number of the rotation's shift at t, negative
+// before the schedule starts.
+func (s *Schedule) shiftIndex(t time.Time) int {
+	local := t.In(s.loc)
+	date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(date.Sub(s.startsOn).Hours() / 24)
+	if s.rotation == RotationWeekly {
+		days = floorDiv(days, 7)
+	}
+
+	k := days
+	for s.handoffAt(k).After(t) {
+		k--
+	}
+	for !s.handoffAt(k + 1).After(t) {
+		k++
+	}
+	return k
+}
+
+// member returns who the rotation puts on call for shift k, if anyone.
+func (s *Schedule) member(k int) string {
+	if k < 0 || len(s.members) == 0 {
+		return ""
+	}
+	return s.members[k%len(s.members)]
+}
+
+// override returns the override in effect at t, if any.
+func (s *Schedule) override(t time.Time) (db.OncallOverride, bool) {
+	for i := len(s.overrides) - 1; i >= 0; i-- {
+		o := s.overrides[i]
+		if !o.StartsAt.Time.After(t) && o.EndsAt.Time.After(t) {
+			return o, true
+		}
+	}
+	return db.OncallOverride{}, false
+}
+
+// Shifts returns the shifts overlapping [from, to), in order. Overrides
+// split the rotation's shifts; consecutive shifts of the same person are
+// merged. Periods when nobody is on call are left out.
+func (s *Schedule) Shifts(from, to time.Time) []Shift {
+	// Overrides in progress at either end extend the period, so that their
+	// shifts are returned whole
+	lo, hi := from, to
+	for _, o := range s.overrides {
+		if o.StartsAt.Time.Before(lo) && o.EndsAt.Time.After(from) {
+			lo = o.StartsAt.Time
+		}
+		if o.EndsAt.Time.After(hi) && o.StartsAt.Time.Before(to) {
+			hi = o.EndsAt.Time
+		}
+	}
+
+	// Every time the person on call may change
+	var edges []time.Time
+	for k := s.shiftIndex(lo); ; k++ {
+		h := s.handoffAt(k)
+		edges = append(edges, h)
+		if !h.Before(hi) {
+			break
+		}
+	}
+	first, last := edges[0], edges[len(edges)-1]
+	for _, o := range s.overrides {
+		for _, t := range []time.Time{o.StartsAt.Time, o.EndsAt.Time} {
+			if t.After(first) && t.Before(last) {
+				edges = append(edges, t)
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].Before(edges[j]) })
+
+	var shifts []Shift
+	for i := 0; i+1 < len(edges); i++ {
+		start, end := edges[i], edges[i+1]
+		if !start.Before(end) {
+			continue
+		}
+
+		shift := Shift{Start: start, End: end}
+		if o, ok := s.override(start); ok {
+			shift.Email, shift.Override = o.Email, true
+		} else {
+			shift.Email = s.member(s.shiftIndex(start))
+		}
+		if shift.Email == "" {
+			continue
+		}
+
+		if n := len(shifts); n > 0 && shifts[n-1].End.Equal(start) &&
+			shifts[n-1].Email == shift.Email && shifts[n-1].Override == shift.Override {
+			shifts[n-1].End = end
+			continue
+		}
+		shifts = append(shifts, shift)
+	}
+
+	overlapping := shifts[:0]
+	for _, shift := range shifts {
+		if shift.End.After(from) && shift.Start.Before(to) {
+			overlapping = append(overlapping, shift)
+		}
+	}
+	return overlapping
+}
+
+// OnCallAt returns the shift in progress at t, or false if nobody is on call.
+func (s *Schedule) OnCallAt(t time.Time) (Shift, bool) {
+	for _, shift := range s.Shifts(t, t.Add(time.Nanosecond)) {
+		if !shift.Start.After(t) && shift.End.After(t) {
+			return shift, true
+		}
+	}
+	return Shift{}, false
+}
+
+// LoadSchedule returns the schedule with its overrides overlapping [from, to).
+func LoadSchedule(ctx context.Context, q *db.Queries, schedule db.OncallSchedule, from, to time.Time) (*Schedule, error) {
+	overrides, err := q.GetOncallOverrides(ctx, db.GetOncallOverridesParams{
+		ScheduleID: schedule.ID,
+		RangeStart: pgtype.Timestamptz{Time: from, Valid: true},
+		RangeEnd:   pgtype.Timestamptz{Time: to, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get on-call overrides: %w", err)
+	}
+	return NewSchedule(schedule, overrides)
+}
+
+// OnCallNotifier emails whoever was on call for the schedule whose ID is the
+// channel's target when the alert was raised, or the schedule's owner if
+// nobody was.
+type OnCallNotifier struct {
+	q     *db.Queries
+	email Notifier
+}
+
+// NewOnCallNotifier creates a notifier sending through the email notifier.
+func NewOnCallNotifier(q *db.Queries, email Notifier) *OnCallNotifier {
+	return &OnCallNotifier{q: q, email: email}
+}
+
+// Send implements Notifier.
+func (n *OnCallNotifier) Send(ctx context.Context, channel db.NotificationChannel, alert Alert) error {
+	scheduleID, err := strconv.ParseInt(channel.Target, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid on-call schedule %q", channel.Target)
+	}
+	row, err := n.q.GetOncallSchedule(ctx, scheduleID)
+	if err != nil {
+		return fmt.Errorf("could not get on-call schedule %d: %w", scheduleID, err)
+	}
+
+	schedule, err := LoadSchedule(ctx, n.q, db.OncallSchedule{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Name:        row.Name,
+		Rotation:    row.Rotation,
+		Members:     row.Members,
+		Timezone:    row.Timezone,
+		StartsOn:    row.StartsOn,
+		HandoffTime: row.HandoffTime,
+		CreatedAt:   row.CreatedAt,
+	}, alert.Time, alert.Time.Add(time.Nanosecond))
+	if err != nil {
+		return err
+	}
+
+	recipient := row.OwnerEmail
+	if shift, ok := schedule.OnCallAt(alert.Time); ok {
+		recipient = shift.Email
+	}
+	return n.email.Send(ctx, db.NotificationChannel{Type: ChannelEmail, Target: recipient}, alert)
+}
+
+// floorDiv divides rounding towards negative infinity.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
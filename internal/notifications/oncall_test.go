@@ -0,0 +1,272 @@
+package notifications
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// 2026-03-01 is a Sunday. In New York, clocks go forward from 02:00 to 03:00
+// on 2026-03-08 and back from 02:00 to 01:00 on 2026-11-01.
+func testSchedule(t *testing.T, rotation string, members []string, overrides ...db.OncallOverride) *Schedule {
+	t.Helper()
+	schedule := db.OncallSchedule{
+		Rotation: rotation,
+		Members:  members,
+		Timezone: "America/New_York",
+	}
+	schedule.StartsOn.Time, schedule.StartsOn.Valid = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), true
+	schedule.HandoffTime.Microseconds, schedule.HandoffTime.Valid = int64(9*time.Hour/time.Microsecond), true
+	s, err := NewSchedule(schedule, overrides)
+	if err != nil {
+		t.Skipf("could not create schedule: %v", err)
+	}
+	return s
+}
+
+func testOverride(email string, start, end time.Time) db.OncallOverride {
+	return db.OncallOverride{
+		Email:    email,
+		StartsAt: pgtype.Timestamptz{Time: start, Valid: true},
+		EndsAt:   pgtype.Timestamptz{Time: end, Valid: true},
+	}
+}
+
+func newYorkTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone not available: %v", err)
+	}
+	parsed, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+	if err != nil {
+		t.Fatalf("invalid time %q: %v", s, err)
+	}
+	return parsed
+}
+
+func utcTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		t.Fatalf("invalid time %q: %v", s, err)
+	}
+	return parsed
+}
+
+func TestScheduleHandoffAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		rotation string
+		k        int
+		want     time.Time
+	}{
+		{"daily first", RotationDaily, 0, utcTime(t, "2026-03-01 14:00")},
+		{"daily before start", RotationDaily, -1, utcTime(t, "2026-02-28 14:00")},
+		{"daily before spring forward", RotationDaily, 6, utcTime(t, "2026-03-07 14:00")},
+		{"daily after spring forward", RotationDaily, 7, utcTime(t, "2026-03-08 13:00")},
+		{"daily after fall back", RotationDaily, 245, utcTime(t, "2026-11-01 14:00")},
+		{"weekly first", RotationWeekly, 0, utcTime(t, "2026-03-01 14:00")},
+		{"weekly after spring forward", RotationWeekly, 1, utcTime(t, "2026-03-08 13:00")},
+		{"weekly before start", RotationWeekly, -1, utcTime(t, "2026-02-22 14:00")},
+	}
+	for _, tt := range tests {
+		s := testSchedule(t, tt.rotation, []string{"a@example.com"})
+		if got := s.handoffAt(tt.k); !got.Equal(tt.want) {
+			t.Errorf("%s: handoffAt(%d) = %v, want %v", tt.name, tt.k, got.UTC(), tt.want)
+		}
+	}
+}
+
+func TestScheduleShiftIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		rotation string
+		t        time.Time
+		want     int
+	}{
+		{"daily before starts_on", RotationDaily, newYorkTime(t, "2026-02-20 12:00"), -9},
+		{"daily before first handoff", RotationDaily, newYorkTime(t, "2026-03-01 08:59"), -1},
+		{"daily at first handoff", RotationDaily, newYorkTime(t, "2026-03-01 09:00"), 0},
+		{"daily after midnight", RotationDaily, newYorkTime(t, "2026-03-02 00:30"), 0},
+		// 13:30 UTC is 08:30 in EST but 09:30 in EDT
+		{"daily after spring forward", RotationDaily, utcTime(t, "2026-03-08 13:30"), 7},
+		{"daily before spring forward handoff", RotationDaily, utcTime(t, "2026-03-08 12:59"), 6},
+		{"daily before fall back handoff", RotationDaily, utcTime(t, "2026-11-01 13:30"), 244},
+		{"daily after fall back", RotationDaily, utcTime(t, "2026-11-01 14:00"), 245},
+		{"weekly before starts_on", RotationWeekly, newYorkTime(t, "2026-02-28 12:00"), -1},
+		{"weekly first", RotationWeekly, newYorkTime(t, "2026-03-07 23:00"), 0},
+		{"weekly before spring forward handoff", RotationWeekly, utcTime(t, "2026-03-08 12:59"), 0},
+		{"weekly after spring forward", RotationWeekly, utcTime(t, "2026-03-08 13:00"), 1},
+	}
+	for _, tt := range tests {
+		s := testSchedule(t, tt.rotation, []string{"a@example.com"})
+		if got := s.shiftIndex(tt.t); got != tt.want {
+			t.Errorf("%s: shiftIndex(%v) = %d, want %d", tt.name, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleShifts(t *testing.T) {
+	members := []string{"a@example.com", "b@example.com", "c@example.com"}
+	tests := []struct {
+		name      string
+		rotation  string
+		members   []string
+		overrides []db.OncallOverride
+		from, to  time.Time
+		want      []Shift
+	}{
+		{
+			name:     "daily across spring forward",
+			rotation: RotationDaily,
+			members:  members,
+			from:     newYorkTime(t, "2026-03-07 00:00"),
+			to:       newYorkTime(t, "2026-03-09 00:00"),
+			want: []Shift{
+				{Email: "c@example.com", Start: utcTime(t, "2026-03-06 14:00"), End: utcTime(t, "2026-03-07 14:00")},
+				{Email: "a@example.com", Start: utcTime(t, "2026-03-07 14:00"), End: utcTime(t, "2026-03-08 13:00")},
+				{Email: "b@example.com", Start: utcTime(t, "2026-03-08 13:00"), End: utcTime(t, "2026-03-09 13:00")},
+			},
+		},
+		{
+			name:     "weekly across spring forward",
+			rotation: RotationWeekly,
+			members:  members,
+			from:     newYorkTime(t, "2026-03-05 00:00"),
+			to:       newYorkTime(t, "2026-03-10 00:00"),
+			want: []Shift{
+				{Email: "a@example.com", Start: utcTime(t, "2026-03-01 14:00"), End: utcTime(t, "2026-03-08 13:00")},
+				{Email: "b@example.com", Start: utcTime(t, "2026-03-08 13:00"), End: utcTime(t, "2026-03-15 13:00")},
+			},
+		},
+		{
+			name:     "before starts_on",
+			rotation: RotationDaily,
+			members:  members,
+			from:     newYorkTime(t, "2026-02-20 00:00"),
+			to:       newYorkTime(t, "2026-03-02 00:00"),
+			want: []Shift{
+				{Email: "a@example.com", Start: utcTime(t, "2026-03-01 14:00"), End: utcTime(t, "2026-03-02 14:00")},
+			},
+		},
+		{
+			name:     "consecutive shifts merged",
+			rotation: RotationDaily,
+			members:  []string{"a@example.com"},
+			from:     newYorkTime(t, "2026-03-01 00:00"),
+			to:       newYorkTime(t, "2026-03-04 00:00"),
+			want: []Shift{
+				{Email: "a@example.com", Start: utcTime(t, "2026-03-01 14:00"), End: utcTime(t, "2026-03-04 14:00")},
+			},
+		},
+		{
+			name:     "override splits a shift",
+			rotation: RotationDaily,
+			members:  members,
+			overrides: []db.OncallOverride{
+				testOverride("x@example.com", newYorkTime(t, "2026-03-07 12:00"), newYorkTime(t, "2026-03-07 14:00")),
+			},
+			from: newYorkTime(t, "2026-03-07 10:00"),
+			to:   newYorkTime(t, "2026-03-07 20:00"),
+			want: []Shift{
+				{Email: "a@example.com", Start: utcTime(t, "2026-03-07 14:00"), End: utcTime(t, "2026-03-07 17:00")},
+				{Email: "x@example.com", Start: utcTime(t, "2026-03-07 17:00"), End: utcTime(t, "2026-03-07 19:00"), Override: true},
+				{Email: "a@example.com", Start: utcTime(t, "2026-03-07 19:00"), End: utcTime(t, "2026-03-08 13:00")},
+			},
+		},
+		{
+			name:     "overlapping overrides",
+			rotation: RotationDaily,
+			members:  members,
+			overrides: []db.OncallOverride{
+				testOverride("x@example.com", newYorkTime(t, "2026-03-02 00:00"), newYorkTime(t, "2026-03-03 00:00")),
+				testOverride("y@example.com", newYorkTime(t, "2026-03-02 12:00"), newYorkTime(t, "2026-03-02 18:00")),
+			},
+			from: newYorkTime(t, "2026-03-02 06:00"),
+			to:   newYorkTime(t, "2026-03-02 20:00"),
+			want: []Shift{
+				{Email: "x@example.com", Start: utcTime(t, "2026-03-02 05:00"), End: utcTime(t, "2026-03-02 17:00"), Override: true},
+				{Email: "y@example.com", Start: utcTime(t, "2026-03-02 17:00"), End: utcTime(t, "2026-03-02 23:00"), Override: true},
+				{Email: "x@example.com", Start: utcTime(t, "2026-03-02 23:00"), End: utcTime(t, "2026-03-03 05:00"), Override: true},
+			},
+		},
+		{
+			name:     "override before starts_on",
+			rotation: RotationDaily,
+			members:  members,
+			overrides: []db.OncallOverride{
+				testOverride("x@example.com", newYorkTime(t, "2026-02-27 09:00"), newYorkTime(t, "2026-02-27 17:00")),
+			},
+			from: newYorkTime(t, "2026-02-27 00:00"),
+			to:   newYorkTime(t, "2026-02-28 00:00"),
+			want: []Shift{
+				{Email: "x@example.com", Start: utcTime(t, "2026-02-27 14:00"), End: utcTime(t, "2026-02-27 22:00"), Override: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testSchedule(t, tt.rotation, tt.members, tt.overrides...)
+			got := s.Shifts(tt.from, tt.to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Shifts = %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i].Email != tt.want[i].Email || got[i].Override != tt.want[i].Override ||
+					!got[i].Start.Equal(tt.want[i].Start) || !got[i].End.Equal(tt.want[i].End) {
+					t.Errorf("shift %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScheduleOnCallAt(t *testing.T) {
+	members := []string{"a@example.com", "b@example.com", "c@example.com"}
+	overrides := []db.OncallOverride{
+		testOverride("x@example.com", newYorkTime(t, "2026-03-02 00:00"), newYorkTime(t, "2026-03-03 00:00")),
+		testOverride("y@example.com", newYorkTime(t, "2026-03-02 12:00"), newYorkTime(t, "2026-03-02 18:00")),
+		testOverride("z@example.com", newYorkTime(t, "2026-02-27 09:00"), newYorkTime(t, "2026-02-27 17:00")),
+	}
+	tests := []struct {
+		name      string
+		t         time.Time
+		wantEmail string // Empty if nobody is on call
+		override  bool
+	}{
+		{"before starts_on", newYorkTime(t, "2026-02-28 12:00"), "", false},
+		{"before first handoff", newYorkTime(t, "2026-03-01 08:59"), "", false},
+		{"override before starts_on", newYorkTime(t, "2026-02-27 12:00"), "z@example.com", true},
+		{"first shift", newYorkTime(t, "2026-03-01 09:00"), "a@example.com", false},
+		{"override", newYorkTime(t, "2026-03-02 10:00"), "x@example.com", true},
+		{"later override wins", newYorkTime(t, "2026-03-02 12:00"), "y@example.com", true},
+		{"earlier override after the later one", newYorkTime(t, "2026-03-02 18:00"), "x@example.com", true},
+		{"rotation after overrides", newYorkTime(t, "2026-03-03 00:00"), "b@example.com", false},
+		{"after spring forward", utcTime(t, "2026-03-08 13:30"), "b@example.com", false},
+		{"before spring forward handoff", utcTime(t, "2026-03-08 12:59"), "a@example.com", false},
+	}
+	s := testSchedule(t, RotationDaily, members, overrides...)
+	for _, tt := range tests {
+		shift, ok := s.OnCallAt(tt.t)
+		if tt.wantEmail == "" {
+			if ok {
+				t.Errorf("%s: OnCallAt = %+v, want nobody", tt.name, shift)
+			}
+			continue
+		}
+		if !ok || shift.Email != tt.wantEmail || shift.Override != tt.override {
+			t.Errorf("%s: OnCallAt = %+v, %v, want %s (override %v)", tt.name, shift, ok, tt.wantEmail, tt.override)
+		}
+	}
+}
+
+func TestFloorDiv(t *testing.T) {
+	got := []int{floorDiv(7, 7), floorDiv(6, 7), floorDiv(0, 7), floorDiv(-1, 7), floorDiv(-7, 7), floorDiv(-8, 7)}
+	if want := []int{1, 0, 0, -1, -1, -2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("floorDiv = %v, want %v", got, want)
+	}
+}
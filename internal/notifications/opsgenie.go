@@ -0,0 +1,76 @@
+package notifications
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"uptime-monitor/internal/database/db"
+)
+
+// Opsgenie limits the alert message to 130 characters.
+const opsgenieMaxMessage = 130
+
+// OpsgenieNotifier creates and closes Opsgenie alerts through the Alert API.
+// The channel target is the API key of an API integration.
+type OpsgenieNotifier struct {
+	q      *db.Queries
+	client *http.Client
+	apiURL string
+}
+
+// NewOpsgenieNotifier creates a notifier for the Opsgenie API at apiURL.
+func NewOpsgenieNotifier(q *db.Queries, apiURL string) *OpsgenieNotifier {
+	return &OpsgenieNotifier{q: q, client: &http.Client{Timeout: webhookTimeout}, apiURL: strings.TrimRight(apiURL, "/")}
+}
+
+// Send implements Notifier. Alerts that don't page are ignored.
+func (n *OpsgenieNotifier) Send(ctx context.Context, channel db.NotificationChannel, alert Alert) error {
+	action, alias, ok := pagingAction(alert)
+	if !ok {
+		return nil
+	}
+
+	headers := map[string]string{"Authorization": "GenieKey " + channel.Target}
+	var endpoint string
+	var payload map[string]interface{}
+	if action == PagingResolve {
+		endpoint = n.apiURL + "/v2/alerts/" + url.PathEscape(alias) + "/close?identifierType=alias"
+		payload = map[string]interface{}{
+			"source": "uptime-monitor",
+			"note":   alert.Message,
+		}
+	} else {
+		endpoint = n.apiURL + "/v2/alerts"
+		payload = opsgeniePayload(alias, alert)
+	}
+
+	statusCode, err := postJSONStatus(ctx, n.client, endpoint, headers, payload)
+	recordPaging(ctx, n.q, channel.ID, alert, action, alias, statusCode, err)
+	return err
+}
+
+func opsgeniePayload(alias string, alert Alert) map[string]interface{} {
+	message := alert.Subject
+	if runes := []rune(message); len(runes) > opsgenieMaxMessage {
+		message = string(runes[:opsgenieMaxMessage])
+	}
+	priority := "P1"
+	if alert.Event == EventCertificateExpiring {
+		priority = "P3"
+	}
+	details := make(map[string]string)
+	for _, f := range alert.Fields() {
+		details[f.Name] = f.Value
+	}
+
+	return map[string]interface{}{
+		"message":     message,
+		"alias":       alias,
+		"description": alert.Message,
+		"priority":    priority,
+		"source":      "uptime-monitor",
+		"entity":      alert.ServiceName,
+		"details":     details,
+	}
+}
@@ -0,0 +1,67 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"uptime-monitor/internal/database/db"
+)
+
+func TestOpsgenieTriggerAndResolve(t *testing.T) {
+	type request struct {
+		path string
+		body map[string]interface{}
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "GenieKey api-key" {
+			t.Errorf("Authorization = %q, want GenieKey api-key", auth)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid body: %v", err)
+		}
+		requests = append(requests, request{r.URL.RequestURI(), body})
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	deliveries := &pagingLog{}
+	notifier := NewOpsgenieNotifier(db.New(deliveries), server.URL+"/")
+	channel := db.NotificationChannel{ID: 4, Type: ChannelOpsgenie, Target: "api-key"}
+
+	up := testAlert
+	up.Event = EventServiceUp
+	up.Status = "up"
+	for _, alert := range []Alert{testAlert, up} {
+		if err := notifier.Send(context.Background(), channel, alert); err != nil {
+			t.Fatalf("Send(%s) failed: %v", alert.Event, err)
+		}
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	create, closeAlert := requests[0], requests[1]
+	if create.path != "/v2/alerts" {
+		t.Errorf("create path = %s, want /v2/alerts", create.path)
+	}
+	if create.body["alias"] != "uptime-monitor-service-7" || create.body["priority"] != "P1" || create.body["message"] != testAlert.Subject {
+		t.Errorf("create body = %v", create.body)
+	}
+	// The alert is closed by the alias it was created with
+	if want := "/v2/alerts/uptime-monitor-service-7/close?identifierType=alias"; closeAlert.path != want {
+		t.Errorf("close path = %s, want %s", closeAlert.path, want)
+	}
+	if closeAlert.body["source"] != "uptime-monitor" {
+		t.Errorf("close body = %v", closeAlert.body)
+	}
+
+	want := [][2]string{{"uptime-monitor-service-7", "trigger"}, {"uptime-monitor-service-7", "resolve"}}
+	if got := deliveries.recorded(); !slices.Equal(got, want) {
+		t.Errorf("recorded deliveries = %v, want %v", got, want)
+	}
+}
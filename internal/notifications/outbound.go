@@ -0,0 +1,249 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// WebhookEventVersion is the version of the event payload sent to webhooks.
+const WebhookEventVersion = "1"
+
+// Headers set on every webhook request.
+const (
+	HeaderWebhookEvent     = "X-Webhook-Event"
+	HeaderWebhookDelivery  = "X-Webhook-Delivery"
+	HeaderWebhookSignature = "X-Webhook-Signature"
+)
+
+// WebhookEvent is the versioned JSON body posted to webhook channels.
+type WebhookEvent struct {
+	Version   string           `json:"version"`
+	ID        string           `json:"id"`
+	Type      string           `json:"type"`
+	CreatedAt time.Time        `json:"created_at"`
+	Data      WebhookEventData `json:"data"`
+}
+
+// WebhookEventData describes the service and what happened to it.
+type WebhookEventData struct {
+	Service        WebhookService      `json:"service"`
+	Status         string              `json:"status,omitempty"`
+	PreviousStatus string              `json:"previous_status,omitempty"`
+	StatusCode     int                 `json:"status_code,omitempty"`
+	ResponseTimeMs int64               `json:"response_time_ms,omitempty"`
+	Error          string              `json:"error,omitempty"`
+	Message        string              `json:"message"`
+	Incident       *WebhookIncident    `json:"incident,omitempty"`
+	Certificate    *WebhookCertificate `json:"certificate,omitempty"`
+	ConfirmURL     string              `json:"confirm_url,omitempty"`     // Posted to by new status page subscribers
+	UnsubscribeURL string              `json:"unsubscribe_url,omitempty"` // Sent to status page subscribers
+}
+
+// WebhookService identifies the service an event is about. Status page
+// subscribers only get the names of the page's components.
+type WebhookService struct {
+	ID     int64  `json:"id,omitempty"`
+	Name   string `json:"name"`
+	Target string `json:"target,omitempty"`
+}
+
+// WebhookIncident is the incident resolved by an incident.resolved event, or
+// the open incident of other events.
+type WebhookIncident struct {
+	ID              int64 `json:"id"`
+	DurationSeconds int64 `json:"duration_seconds"`
+	EscalationLevel int   `json:"escalation_level,omitempty"`
+}
+
+// WebhookCertificate describes the certificate of certificate.expiring and
+// certificate.renewed events.
+type WebhookCertificate struct {
+	DaysRemaining int       `json:"days_remaining"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// WebhookNotifier posts signed events to user-configured URLs and records
+// every attempt in webhook_deliveries. Failed events are retried by the
+// dispatcher with the rest of the notification, under the same IDs.
+//
+// Requests carry an X-Webhook-Signature header of the form "t=<unix>,v1=<hex>"
+// where v1 is the HMAC-SHA256, keyed with the channel secret, of the
+// timestamp, a dot and the raw request body. Receivers should reject stale
+// timestamps to prevent replays.
+type WebhookNotifier struct {
+	q      *db.Queries
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a new webhook notifier that logs deliveries with q.
+func NewWebhookNotifier(q *db.Queries) *WebhookNotifier {
+	return &WebhookNotifier{q: q, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// NewPublicWebhookNotifier creates a webhook notifier that only connects to
+// public addresses, for the webhooks of status page subscribers, which
+// anyone can register.
+func NewPublicWebhookNotifier(q *db.Queries) *WebhookNotifier {
+	return &WebhookNotifier{q: q, client: publicClient(webhookTimeout)}
+}
+
+// Send implements Notifier. A recovery that resolved an incident is sent as a
+// service.up event followed by an incident.resolved event.
+func (n *WebhookNotifier) Send(ctx context.Context, channel db.NotificationChannel, alert Alert) error {
+	if err := n.deliver(ctx, channel, newWebhookEvent(alert.Event, alert), alert.Attempt); err != nil {
+		return err
+	}
+	if alert.ResolvedIncidentID != 0 {
+		return n.deliver(ctx, channel, newWebhookEvent(EventIncidentResolved, alert), alert.Attempt)
+	}
+	return nil
+}
+
+func newWebhookEvent(eventType string, alert Alert) WebhookEvent {
+	event := WebhookEvent{
+		Version:   WebhookEventVersion,
+		ID:        eventID(alert, eventType),
+		Type:      eventType,
+		CreatedAt: alert.Time.UTC(),
+		Data: WebhookEventData{
+			Service: WebhookService{
+				ID:     alert.ServiceID,
+				Name:   alert.ServiceName,
+				Target: alert.Target,
+			},
+			Status:         alert.Status,
+			PreviousStatus: alert.PreviousStatus,
+			StatusCode:     alert.StatusCode,
+			ResponseTimeMs: alert.ResponseTime.Milliseconds(),
+			Error:          alert.Error,
+			Message:        alert.Message,
+			ConfirmURL:     alert.ConfirmURL,
+			UnsubscribeURL: alert.UnsubscribeURL,
+		},
+	}
+	if alert.ResolvedIncidentID != 0 {
+		event.Data.Incident = &WebhookIncident{
+			ID:              alert.ResolvedIncidentID,
+			DurationSeconds: int64(alert.IncidentDuration.Seconds()),
+		}
+	} else if alert.IncidentID != 0 {
+		event.Data.Incident = &WebhookIncident{ID: alert.IncidentID, EscalationLevel: alert.EscalationLevel}
+	}
+	if eventType == EventCertificateExpiring || eventType == EventCertificateRenewed {
+		event.Data.Certificate = &WebhookCertificate{
+			DaysRemaining: alert.CertDaysRemaining,
+			ExpiresAt:     alert.CertExpiresAt.UTC(),
+		}
+	}
+	return event
+}
+
+// eventID identifies an event of the given type about the alert. Events of
+// queued alerts are named after the notification, so that every attempt to
+// deliver it, including the events already delivered by a failed attempt,
+// carries the same ID and receivers can deduplicate retries.
+func eventID(alert Alert, eventType string) string {
+	if alert.NotificationID != 0 {
+		return fmt.Sprintf("evt_%d_%s", alert.NotificationID, eventType)
+	}
+	return newEventID()
+}
+
+// newEventID returns a random event identifier.
+func newEventID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return "evt_" + hex.EncodeToString(b)
+}
+
+// SignWebhook returns the signature header value for body sent at timestamp.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// deliver posts the event once and records the attempt.
+func (n *WebhookNotifier) deliver(ctx context.Context, channel db.NotificationChannel, event WebhookEvent, attempt int) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	start := time.Now()
+	statusCode, err := n.post(ctx, channel, event, body)
+	n.record(ctx, channel.ID, event, attempt, statusCode, time.Since(start), err)
+	if err != nil {
+		return fmt.Errorf("%s not delivered: %w", event.Type, err)
+	}
+	return nil
+}
+
+// post sends one attempt and returns the response status code, if any.
+func (n *WebhookNotifier) post(ctx context.Context, channel db.NotificationChannel, event WebhookEvent, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.Target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "uptime-monitor-webhook/"+WebhookEventVersion)
+	req.Header.Set(HeaderWebhookEvent, event.Type)
+	req.Header.Set(HeaderWebhookDelivery, event.ID)
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(channel.Secret.String, time.Now(), body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// record logs a delivery attempt. Failing to log does not fail the delivery.
+// Attempts to status page subscribers, which have no channel, are not logged.
+func (n *WebhookNotifier) record(ctx context.Context, channelID int64, event WebhookEvent, attempt, statusCode int, duration time.Duration, deliveryErr error) {
+	if channelID == 0 {
+		return
+	}
+	params := db.CreateWebhookDeliveryParams{
+		ChannelID:  channelID,
+		EventID:    event.ID,
+		EventType:  event.Type,
+		Attempt:    int32(attempt),
+		DurationMs: int32(duration.Milliseconds()),
+		Success:    deliveryErr == nil,
+	}
+	if statusCode != 0 {
+		params.StatusCode = pgtype.Int4{Int32: int32(statusCode), Valid: true}
+	}
+	if deliveryErr != nil {
+		params.Error = pgtype.Text{String: deliveryErr.Error(), Valid: true}
+	}
+	if err := n.q.CreateWebhookDelivery(context.WithoutCancel(ctx), params); err != nil {
+		log.Printf("ERROR: Could not record webhook delivery for channel %d: %v", channelID, err)
+	}
+}
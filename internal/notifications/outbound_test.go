@@ -0,0 +1,94 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"uptime-monitor/internal/database/db"
+)
+
+func TestSignWebhook(t *testing.T) {
+	got := SignWebhook("whsec_test", time.Unix(1700000000, 0), []byte(`{"id":"evt_1"}`))
+	want := "t=1700000000,v1=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925"
+	if got != want {
+		t.Errorf("SignWebhook = %s, want %s", got, want)
+	}
+}
+
+func TestWebhookRetryKeepsEventIDs(t *testing.T) {
+	type request struct {
+		eventType, delivery, id string
+	}
+	var requests []request
+	failResolved := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event WebhookEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("invalid event: %v", err)
+		}
+
+		// The signature covers the timestamp it was sent with
+		signature := r.Header.Get(HeaderWebhookSignature)
+		ts, _, _ := strings.Cut(strings.TrimPrefix(signature, "t="), ",")
+		unix, _ := strconv.ParseInt(ts, 10, 64)
+		if want := SignWebhook("secret", time.Unix(unix, 0), body); signature != want {
+			t.Errorf("signature = %s, want %s", signature, want)
+		}
+
+		requests = append(requests, request{r.Header.Get(HeaderWebhookEvent), r.Header.Get(HeaderWebhookDelivery), event.ID})
+		if event.Type == EventIncidentResolved && failResolved {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(nil)
+	channel := db.NotificationChannel{Type: ChannelWebhook, Target: server.URL}
+	channel.Secret.String, channel.Secret.Valid = "secret", true
+
+	alert := testAlert
+	alert.Event = EventServiceUp
+	alert.Status = "up"
+	alert.ResolvedIncidentID = 3
+	alert.NotificationID = 42
+
+	// The dispatcher retries the whole alert after a failure
+	alert.Attempt = 1
+	if err := notifier.Send(context.Background(), channel, alert); err == nil {
+		t.Fatal("Send succeeded although incident.resolved failed")
+	}
+	failResolved = false
+	alert.Attempt = 2
+	if err := notifier.Send(context.Background(), channel, alert); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	want := []request{
+		{EventServiceUp, "evt_42_service.up", "evt_42_service.up"},
+		{EventIncidentResolved, "evt_42_incident.resolved", "evt_42_incident.resolved"},
+		{EventServiceUp, "evt_42_service.up", "evt_42_service.up"},
+		{EventIncidentResolved, "evt_42_incident.resolved", "evt_42_incident.resolved"},
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("got %d requests %v, want %d", len(requests), requests, len(want))
+	}
+	for i := range want {
+		if requests[i] != want[i] {
+			t.Errorf("request %d = %+v, want %+v", i+1, requests[i], want[i])
+		}
+	}
+}
+
+func TestWebhookEventIDWithoutNotification(t *testing.T) {
+	first, second := eventID(testAlert, EventServiceDown), eventID(testAlert, EventServiceDown)
+	if !strings.HasPrefix(first, "evt_") || first == second {
+		t.Errorf("event IDs of alerts not sent from the outbox = %s, %s, want distinct random IDs", first, second)
+	}
+}
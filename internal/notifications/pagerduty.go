@@ -0,0 +1,63 @@
+package notifications
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"uptime-monitor/internal/database/db"
+)
+
+// PagerDutyNotifier triggers and resolves PagerDuty incidents through the
+// Events API v2. The channel target is the integration routing key.
+type PagerDutyNotifier struct {
+	q         *db.Queries
+	client    *http.Client
+	eventsURL string
+}
+
+// NewPagerDutyNotifier creates a notifier that posts to the Events API at eventsURL.
+func NewPagerDutyNotifier(q *db.Queries, eventsURL string) *PagerDutyNotifier {
+	return &PagerDutyNotifier{q: q, client: &http.Client{Timeout: webhookTimeout}, eventsURL: eventsURL}
+}
+
+// Send implements Notifier. Alerts that don't page are ignored.
+func (n *PagerDutyNotifier) Send(ctx context.Context, channel db.NotificationChannel, alert Alert) error {
+	action, dedupKey, ok := pagingAction(alert)
+	if !ok {
+		return nil
+	}
+
+	statusCode, err := postJSONStatus(ctx, n.client, n.eventsURL, nil, pagerDutyPayload(channel.Target, action, dedupKey, alert))
+	recordPaging(ctx, n.q, channel.ID, alert, action, dedupKey, statusCode, err)
+	return err
+}
+
+func pagerDutyPayload(routingKey, action, dedupKey string, alert Alert) map[string]interface{} {
+	payload := map[string]interface{}{
+		"routing_key":  routingKey,
+		"event_action": action,
+		"dedup_key":    dedupKey,
+	}
+	if action == PagingResolve {
+		return payload
+	}
+
+	severity := "critical"
+	if alert.Event == EventCertificateExpiring {
+		severity = "warning"
+	}
+	details := make(map[string]string)
+	for _, f := range alert.Fields() {
+		details[f.Name] = f.Value
+	}
+
+	payload["payload"] = map[string]interface{}{
+		"summary":        alert.Subject,
+		"source":         alert.Target,
+		"severity":       severity,
+		"timestamp":      alert.Time.UTC().Format(time.RFC3339),
+		"component":      alert.ServiceName,
+		"custom_details": details,
+	}
+	return payload
+}
@@ -0,0 +1,80 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"uptime-monitor/internal/database/db"
+)
+
+func TestPagerDutyTriggerAndResolve(t *testing.T) {
+	var events []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("invalid event: %v", err)
+		}
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	deliveries := &pagingLog{}
+	notifier := NewPagerDutyNotifier(db.New(deliveries), server.URL)
+	channel := db.NotificationChannel{ID: 3, Type: ChannelPagerDuty, Target: "routing-key"}
+
+	up := testAlert
+	up.Event = EventServiceUp
+	up.Status = "up"
+	for _, alert := range []Alert{testAlert, up} {
+		if err := notifier.Send(context.Background(), channel, alert); err != nil {
+			t.Fatalf("Send(%s) failed: %v", alert.Event, err)
+		}
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	trigger, resolve := events[0], events[1]
+	if trigger["event_action"] != "trigger" || resolve["event_action"] != "resolve" {
+		t.Errorf("event actions = %v, %v, want trigger, resolve", trigger["event_action"], resolve["event_action"])
+	}
+	if trigger["dedup_key"] != "uptime-monitor-service-7" || resolve["dedup_key"] != trigger["dedup_key"] {
+		t.Errorf("dedup keys = %v, %v, want uptime-monitor-service-7 for both", trigger["dedup_key"], resolve["dedup_key"])
+	}
+	for _, event := range events {
+		if event["routing_key"] != "routing-key" {
+			t.Errorf("routing_key = %v, want routing-key", event["routing_key"])
+		}
+	}
+	payload, _ := trigger["payload"].(map[string]interface{})
+	if payload["summary"] != testAlert.Subject || payload["severity"] != "critical" || payload["source"] != testAlert.Target {
+		t.Errorf("trigger payload = %v", payload)
+	}
+	if _, ok := resolve["payload"]; ok {
+		t.Error("resolve event has a payload")
+	}
+
+	want := [][2]string{{"uptime-monitor-service-7", "trigger"}, {"uptime-monitor-service-7", "resolve"}}
+	if got := deliveries.recorded(); !slices.Equal(got, want) {
+		t.Errorf("recorded deliveries = %v, want %v", got, want)
+	}
+}
+
+func TestPagerDutyIgnoresAlertsThatDontPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	}))
+	defer server.Close()
+
+	degraded := testAlert
+	degraded.Event = EventServiceDegraded
+	degraded.PreviousStatus = "up"
+	notifier := NewPagerDutyNotifier(db.New(&pagingLog{}), server.URL)
+	if err := notifier.Send(context.Background(), db.NotificationChannel{Target: "routing-key"}, degraded); err != nil {
+		t.Errorf("Send failed: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Paging actions recorded in paging_deliveries.action.
+const (
+	PagingTrigger = "trigger"
+	PagingResolve = "resolve"
+)
+
+// pagingAction returns the paging action and dedup key for an alert, or false
+// if the alert should not page. Status alerts share a key per service so that
+// recovery resolves the page opened by the outage. Leaving the down state
+// resolves the page like it resolves the incident, even if the service is
+// only degraded, which doesn't page by itself. A flapping service pages until
+// it stabilizes in a state other than down. Certificate pages are resolved
+// once the certificate is renewed.
+//
+// Pages about a service are delivered to each channel in the order they were
+// queued (see ClaimOutboxNotifications), so a trigger that is retried never
+// reopens a page resolved after it.
+func pagingAction(alert Alert) (action, dedupKey string, ok bool) {
+	serviceKey := fmt.Sprintf("uptime-monitor-service-%d", alert.ServiceID)
+	switch alert.Event {
+	case EventServiceDown, EventServiceFlapping, EventIncidentEscalated:
+		return PagingTrigger, serviceKey, true
+	case EventServiceUp:
+		return PagingResolve, serviceKey, true
+	case EventServiceDegraded:
+		if alert.PreviousStatus == "down" {
+			return PagingResolve, serviceKey, true
+		}
+		return "", "", false
+	case EventServiceStabilized:
+		switch alert.Status {
+		case "up", "degraded":
+			return PagingResolve, serviceKey, true
+		case "down":
+			return PagingTrigger, serviceKey, true
+		}
+		return "", "", false
+	case EventCertificateExpiring:
+		return PagingTrigger, fmt.Sprintf("uptime-monitor-service-%d-certificate", alert.ServiceID), true
+	case EventCertificateRenewed:
+		return PagingResolve, fmt.Sprintf("uptime-monitor-service-%d-certificate", alert.ServiceID), true
+	default:
+		return "", "", false
+	}
+}
+
+// recordPaging logs a paging request. Failing to log does not fail the delivery.
+func recordPaging(ctx context.Context, q *db.Queries, channelID int64, alert Alert, action, dedupKey string, statusCode int, deliveryErr error) {
+	params := db.CreatePagingDeliveryParams{
+		ChannelID: channelID,
+		ServiceID: alert.ServiceID,
+		DedupKey:  dedupKey,
+		Action:    action,
+		Success:   deliveryErr == nil,
+	}
+	if statusCode != 0 {
+		params.StatusCode = pgtype.Int4{Int32: int32(statusCode), Valid: true}
+	}
+	if deliveryErr != nil {
+		params.Error = pgtype.Text{String: deliveryErr.Error(), Valid: true}
+	}
+	if err := q.CreatePagingDelivery(context.WithoutCancel(ctx), params); err != nil {
+		log.Printf("ERROR: Could not record paging delivery for channel %d: %v", channelID, err)
+	}
+}
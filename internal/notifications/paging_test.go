@@ -0,0 +1,74 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pagingLog is a db.DBTX that keeps the paging deliveries recorded through it.
+type pagingLog struct {
+	mu         sync.Mutex
+	deliveries [][]interface{}
+}
+
+func (l *pagingLog) Exec(_ context.Context, _ string, args ...interface{}) (pgconn.CommandTag, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.deliveries = append(l.deliveries, args)
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (l *pagingLog) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return nil, errors.New("unexpected query")
+}
+
+func (l *pagingLog) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	panic("unexpected query")
+}
+
+// recorded returns the dedup key and action of each recorded delivery.
+func (l *pagingLog) recorded() [][2]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var recorded [][2]string
+	for _, args := range l.deliveries {
+		recorded = append(recorded, [2]string{args[2].(string), args[3].(string)})
+	}
+	return recorded
+}
+
+func TestPagingAction(t *testing.T) {
+	tests := []struct {
+		name   string
+		alert  Alert
+		action string
+		key    string
+		ok     bool
+	}{
+		{"down", Alert{Event: EventServiceDown, ServiceID: 7}, PagingTrigger, "uptime-monitor-service-7", true},
+		{"up", Alert{Event: EventServiceUp, ServiceID: 7}, PagingResolve, "uptime-monitor-service-7", true},
+		{"flapping", Alert{Event: EventServiceFlapping, ServiceID: 7}, PagingTrigger, "uptime-monitor-service-7", true},
+		{"escalated", Alert{Event: EventIncidentEscalated, ServiceID: 7}, PagingTrigger, "uptime-monitor-service-7", true},
+		{"stabilized up", Alert{Event: EventServiceStabilized, ServiceID: 7, Status: "up"}, PagingResolve, "uptime-monitor-service-7", true},
+		{"stabilized down", Alert{Event: EventServiceStabilized, ServiceID: 7, Status: "down"}, PagingTrigger, "uptime-monitor-service-7", true},
+		{"stabilized degraded", Alert{Event: EventServiceStabilized, ServiceID: 7, Status: "degraded"}, PagingResolve, "uptime-monitor-service-7", true},
+		{"down to degraded", Alert{Event: EventServiceDegraded, ServiceID: 7, PreviousStatus: "down"}, PagingResolve, "uptime-monitor-service-7", true},
+		{"up to degraded", Alert{Event: EventServiceDegraded, ServiceID: 7, PreviousStatus: "up"}, "", "", false},
+		{"certificate", Alert{Event: EventCertificateExpiring, ServiceID: 7}, PagingTrigger, "uptime-monitor-service-7-certificate", true},
+		{"certificate renewed", Alert{Event: EventCertificateRenewed, ServiceID: 7}, PagingResolve, "uptime-monitor-service-7-certificate", true},
+		{"incident resolved", Alert{Event: EventIncidentResolved, ServiceID: 7}, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, key, ok := pagingAction(tt.alert)
+			if action != tt.action || key != tt.key || ok != tt.ok {
+				t.Errorf("pagingAction = (%q, %q, %v), want (%q, %q, %v)", action, key, ok, tt.action, tt.key, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"uptime-monitor/internal/database/db"
+)
+
+// Slack limits the text of header blocks to 150 characters.
+const slackMaxHeader = 150
+
+// SlackNotifier posts alerts to Slack incoming webhooks using Block Kit.
+type SlackNotifier struct {
+	client *http.Client
+}
+
+// NewSlackNotifier creates a new Slack notifier.
+func NewSlackNotifier() *SlackNotifier {
+	return &SlackNotifier{client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// Send implements Notifier.
+func (n *SlackNotifier) Send(ctx context.Context, channel db.NotificationChannel, alert Alert) error {
+	return postJSON(ctx, n.client, channel.Target, slackPayload(alert))
+}
+
+func slackPayload(alert Alert) map[string]interface{} {
+	var fields []map[string]interface{}
+	for _, f := range alert.Fields() {
+		fields = append(fields, map[string]interface{}{
+			"type": "mrkdwn",
+			"text": fmt.Sprintf("*%s*\n%s", f.Name, f.Value),
+		})
+	}
+
+	header := alert.Subject
+	if runes := []rune(header); len(runes) > slackMaxHeader {
+		header = string(runes[:slackMaxHeader-1]) + "…"
+	}
+	blocks := []map[string]interface{}{
+		{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": header},
+		},
+	}
+	// Slack rejects sections with empty text
+	if alert.Message != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": alert.Message},
+		})
+	}
+	// Slack allows at most 10 fields per section
+	for len(fields) > 0 {
+		n := len(fields)
+		if n > 10 {
+			n = 10
+		}
+		blocks = append(blocks, map[string]interface{}{"type": "section", "fields": fields[:n]})
+		fields = fields[n:]
+	}
+	blocks = append(blocks, map[string]interface{}{
+		"type": "context",
+		"elements": []map[string]interface{}{
+			{"type": "mrkdwn", "text": fmt.Sprintf("Checked at <!date^%d^{date_short_pretty} {time_secs}|%s>",
+				alert.Time.Unix(), alert.Time.UTC().Format("2006-01-02 15:04:05 UTC"))},
+		},
+	})
+
+	// The attachment adds a status colored bar; text is the notification fallback.
+	return map[string]interface{}{
+		"text": alert.Subject,
+		"attachments": []map[string]interface{}{
+			{"color": statusColor(alert.Status), "blocks": blocks},
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestSlackPayload(t *testing.T) {
+	got := sendToServer(t, NewSlackNotifier(), testAlert)
+	assertJSON(t, got, `{
+		"text": "Service Alert: API is DOWN",
+		"attachments": [{
+			"color": "#e01e5a",
+			"blocks": [
+				{"type": "header", "text": {"type": "plain_text", "text": "Service Alert: API is DOWN"}},
+				{"type": "section", "text": {"type": "mrkdwn", "text": "The service 'API' is down."}},
+				{"type": "section", "fields": [
+					{"type": "mrkdwn", "text": "*Service*\nAPI"},
+					{"type": "mrkdwn", "text": "*Target*\nhttps://api.example.com/health"},
+					{"type": "mrkdwn", "text": "*Status*\ndown"},
+					{"type": "mrkdwn", "text": "*Status code*\n503"},
+					{"type": "mrkdwn", "text": "*Latency*\n1234ms"},
+					{"type": "mrkdwn", "text": "*Error*\nunexpected status code 503"}
+				]},
+				{"type": "context", "elements": [
+					{"type": "mrkdwn", "text": "Checked at <!date^1772368245^{date_short_pretty} {time_secs}|2026-03-01 12:30:45 UTC>"}
+				]}
+			]
+		}]
+	}`)
+}
+
+func TestSlackPayloadLongSubject(t *testing.T) {
+	alert := testAlert
+	alert.Subject = "Service Alert: " + strings.Repeat("é", 200)
+	got := sendToServer(t, NewSlackNotifier(), alert)
+
+	header := "Service Alert: " + strings.Repeat("é", slackMaxHeader-len("Service Alert: ")-1) + "…"
+	blocks := got.(map[string]interface{})["attachments"].([]interface{})[0].(map[string]interface{})["blocks"].([]interface{})
+	text := blocks[0].(map[string]interface{})["text"].(map[string]interface{})["text"]
+	if text != header {
+		t.Errorf("header = %q, want %q", text, header)
+	}
+	if n := utf8.RuneCountInString(header); n != slackMaxHeader {
+		t.Errorf("header has %d characters, want %d", n, slackMaxHeader)
+	}
+	if fallback := got.(map[string]interface{})["text"]; fallback != alert.Subject {
+		t.Errorf("text = %q, want the whole subject", fallback)
+	}
+}
+
+func TestSlackPayloadWithoutMessageOrFields(t *testing.T) {
+	got := sendToServer(t, NewSlackNotifier(), Alert{
+		Event:   EventServiceDown,
+		Subject: "Service Alert: API is DOWN",
+		Time:    time.Date(2026, 3, 1, 12, 30, 45, 0, time.UTC),
+	})
+	assertJSON(t, got, `{
+		"text": "Service Alert: API is DOWN",
+		"attachments": [{
+			"color": "#1d9bd1",
+			"blocks": [
+				{"type": "header", "text": {"type": "plain_text", "text": "Service Alert: API is DOWN"}},
+				{"type": "context", "elements": [
+					{"type": "mrkdwn", "text": "Checked at <!date^1772368245^{date_short_pretty} {time_secs}|2026-03-01 12:30:45 UTC>"}
+				]}
+			]
+		}]
+	}`)
+}
+
+func TestSlackPayloadWithoutFields(t *testing.T) {
+	got := sendToServer(t, NewSlackNotifier(), Alert{
+		Event:   EventServiceDown,
+		Status:  "down",
+		Subject: "Service Alert: API is DOWN",
+		Message: "The service 'API' is down.",
+		Time:    time.Date(2026, 3, 1, 12, 30, 45, 0, time.UTC),
+	})
+	assertJSON(t, got, `{
+		"text": "Service Alert: API is DOWN",
+		"attachments": [{
+			"color": "#e01e5a",
+			"blocks": [
+				{"type": "header", "text": {"type": "plain_text", "text": "Service Alert: API is DOWN"}},
+				{"type": "section", "text": {"type": "mrkdwn", "text": "The service 'API' is down."}},
+				{"type": "section", "fields": [
+					{"type": "mrkdwn", "text": "*Status*\ndown"}
+				]},
+				{"type": "context", "elements": [
+					{"type": "mrkdwn", "text": "Checked at <!date^1772368245^{date_short_pretty} {time_secs}|2026-03-01 12:30:45 UTC>"}
+				]}
+			]
+		}]
+	}`)
+}
@@ -0,0 +1,129 @@
+package notifications
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+	"uptime-monitor/internal/config"
+)
+
+// smtpTimeout bounds a whole SMTP session, from connecting to QUIT.
+const smtpTimeout = 30 * time.Second
+
+// sendMail delivers msg over SMTP using the configured transport security and
+// authentication. Unlike smtp.SendMail, STARTTLS is required rather than used
+// opportunistically, and implicit TLS (SMTPS, usually port 465) is supported.
+func sendMail(ctx context.Context, cfg *config.Config, from string, to []string, msg []byte) error {
+	addr := net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort)
+	tlsConfig := &tls.Config{ServerName: cfg.SMTPHost}
+
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	var err error
+	if cfg.SMTPSecurity == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("could not connect to SMTP server: %w", err)
+	}
+
+	deadline := time.Now().Add(smtpTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if cfg.SMTPSecurity == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("SMTP server does not support STARTTLS")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	if auth := smtpAuth(cfg); auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// smtpAuth returns the configured authentication mechanism, or nil for none.
+func smtpAuth(cfg *config.Config) smtp.Auth {
+	switch cfg.SMTPAuth {
+	case "none":
+		return nil
+	case "login":
+		return &loginAuth{username: cfg.SMTPUsername, password: cfg.SMTPPassword, host: cfg.SMTPHost}
+	case "cram-md5":
+		return smtp.CRAMMD5Auth(cfg.SMTPUsername, cfg.SMTPPassword)
+	default:
+		return smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+}
+
+// loginAuth implements the LOGIN mechanism, which net/smtp does not provide
+// but some providers still require. Like smtp.PlainAuth, it refuses to send
+// credentials over an unencrypted connection except to localhost.
+type loginAuth struct {
+	username, password, host string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && a.host != "localhost" && a.host != "127.0.0.1" && a.host != "::1" {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	challenge := strings.ToLower(string(fromServer))
+	switch {
+	case strings.HasPrefix(challenge, "user"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(challenge, "pass"):
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected LOGIN challenge %q", fromServer)
+}
@@ -0,0 +1,82 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"uptime-monitor/internal/database/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SubscriptionURL returns the link confirming ("confirm") or cancelling
+// ("unsubscribe") the subscription with the given token to a status page.
+func SubscriptionURL(baseURL, slug, action, token string) string {
+	return fmt.Sprintf("%s/status/%s/%s?%s", baseURL, slug, action, url.Values{"token": {token}}.Encode())
+}
+
+// SubscriberNotifier delivers alerts to the status page subscriber whose ID
+// is the channel's target, by email or webhook, with links to confirm or
+// cancel the subscription. Alerts to subscribers who have since unsubscribed
+// are dropped, as are updates to those who have not confirmed yet.
+type SubscriberNotifier struct {
+	q         *db.Queries
+	publicURL string
+	email     Notifier
+	webhook   Notifier
+}
+
+// NewSubscriberNotifier creates a notifier sending through the email and
+// webhook notifiers. Links start with publicURL.
+func NewSubscriberNotifier(q *db.Queries, publicURL string, email, webhook Notifier) *SubscriberNotifier {
+	return &SubscriberNotifier{q: q, publicURL: publicURL, email: email, webhook: webhook}
+}
+
+// Send implements Notifier.
+func (n *SubscriberNotifier) Send(ctx context.Context, channel db.NotificationChannel, alert Alert) error {
+	subscriberID, err := strconv.ParseInt(channel.Target, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid status page subscriber %q", channel.Target)
+	}
+	subscriber, err := n.q.GetStatusPageSubscriber(ctx, subscriberID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil // Unsubscribed
+		}
+		return fmt.Errorf("could not get status page subscriber %d: %w", subscriberID, err)
+	}
+
+	if alert.Event == EventSubscriptionConfirm {
+		if subscriber.ConfirmedAt.Valid {
+			return nil
+		}
+		if n.publicURL == "" {
+			return fmt.Errorf("PUBLIC_URL is not set, confirmation links cannot be sent")
+		}
+		alert.ConfirmURL = SubscriptionURL(n.publicURL, subscriber.PageSlug, "confirm", subscriber.Token)
+	} else if !subscriber.ConfirmedAt.Valid {
+		return nil
+	}
+	if n.publicURL != "" {
+		alert.UnsubscribeURL = SubscriptionURL(n.publicURL, subscriber.PageSlug, "unsubscribe", subscriber.Token)
+	}
+
+	// Service IDs are internal to the page's owner
+	alert.ServiceID = 0
+
+	switch subscriber.Type {
+	case ChannelEmail:
+		// Subscribers cannot acknowledge incidents
+		alert.IncidentID = 0
+		return n.email.Send(ctx, db.NotificationChannel{Type: ChannelEmail, Target: subscriber.Target}, alert)
+	case ChannelWebhook:
+		return n.webhook.Send(ctx, db.NotificationChannel{
+			Type:   ChannelWebhook,
+			Target: subscriber.Target,
+			Secret: subscriber.Secret,
+		}, alert)
+	}
+	return fmt.Errorf("unknown status page subscriber type %q", subscriber.Type)
+}
@@ -0,0 +1,75 @@
+package notifications
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"uptime-monitor/internal/database/db"
+)
+
+// TeamsNotifier posts alerts to Microsoft Teams incoming webhooks as Adaptive Cards.
+type TeamsNotifier struct {
+	client *http.Client
+}
+
+// NewTeamsNotifier creates a new Teams notifier.
+func NewTeamsNotifier() *TeamsNotifier {
+	return &TeamsNotifier{client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// Send implements Notifier.
+func (n *TeamsNotifier) Send(ctx context.Context, channel db.NotificationChannel, alert Alert) error {
+	return postJSON(ctx, n.client, channel.Target, teamsPayload(alert))
+}
+
+// teamsStatusStyle maps a status to an Adaptive Card text color.
+func teamsStatusStyle(status string) string {
+	switch status {
+	case "up":
+		return "good"
+	case "degraded":
+		return "warning"
+	case "down":
+		return "attention"
+	default:
+		return "accent"
+	}
+}
+
+func teamsPayload(alert Alert) map[string]interface{} {
+	var facts []map[string]interface{}
+	for _, f := range alert.Fields() {
+		facts = append(facts, map[string]interface{}{"title": f.Name, "value": f.Value})
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body": []map[string]interface{}{
+			{
+				"type":   "TextBlock",
+				"text":   alert.Subject,
+				"size":   "Large",
+				"weight": "Bolder",
+				"color":  teamsStatusStyle(alert.Status),
+				"wrap":   true,
+			},
+			{"type": "TextBlock", "text": alert.Message, "wrap": true},
+			{"type": "FactSet", "facts": facts},
+			{
+				"type":     "TextBlock",
+				"text":     "Checked at " + alert.Time.UTC().Format(time.RFC1123),
+				"isSubtle": true,
+				"size":     "Small",
+			},
+		},
+	}
+
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+}
@@ -0,0 +1,31 @@
+package notifications
+
+import "testing"
+
+func TestTeamsPayload(t *testing.T) {
+	got := sendToServer(t, NewTeamsNotifier(), testAlert)
+	assertJSON(t, got, `{
+		"type": "message",
+		"attachments": [{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": {
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type": "AdaptiveCard",
+				"version": "1.4",
+				"body": [
+					{"type": "TextBlock", "text": "Service Alert: API is DOWN", "size": "Large", "weight": "Bolder", "color": "attention", "wrap": true},
+					{"type": "TextBlock", "text": "The service 'API' is down.", "wrap": true},
+					{"type": "FactSet", "facts": [
+						{"title": "Service", "value": "API"},
+						{"title": "Target", "value": "https://api.example.com/health"},
+						{"title": "Status", "value": "down"},
+						{"title": "Status code", "value": "503"},
+						{"title": "Latency", "value": "1234ms"},
+						{"title": "Error", "value": "unexpected status code 503"}
+					]},
+					{"type": "TextBlock", "text": "Checked at Sun, 01 Mar 2026 12:30:45 UTC", "isSubtle": true, "size": "Small"}
+				]
+			}
+		}]
+	}`)
+}
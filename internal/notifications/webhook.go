@@ -0,0 +1,73 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// webhookTimeout bounds a single request to a chat webhook.
+const webhookTimeout = 10 * time.Second
+
+// Status colors used by the chat integrations.
+const (
+	colorUp       = "#2eb67d"
+	colorDegraded = "#ecb22e"
+	colorDown     = "#e01e5a"
+	colorInfo     = "#1d9bd1"
+)
+
+// statusColor returns the color for the alert status as a hex string.
+func statusColor(status string) string {
+	switch status {
+	case "up", "resolved":
+		return colorUp
+	case "degraded", "flapping":
+		return colorDegraded
+	case "down":
+		return colorDown
+	default:
+		return colorInfo
+	}
+}
+
+// postJSON sends payload to an incoming webhook URL and fails on any non-2xx response.
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	_, err := postJSONStatus(ctx, client, url, nil, payload)
+	return err
+}
+
+// postJSONStatus is postJSON with extra request headers that also returns
+// the response status code, if a response was received.
+func postJSONStatus(ctx context.Context, client *http.Client, url string, headers map[string]string, payload interface{}) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp.StatusCode, nil
+}
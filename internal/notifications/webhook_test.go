@@ -0,0 +1,80 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"uptime-monitor/internal/database/db"
+)
+
+// testAlert is a service.down alert with every field shown in chat messages.
+var testAlert = Alert{
+	Event:        EventServiceDown,
+	ServiceID:    7,
+	ServiceName:  "API",
+	Target:       "https://api.example.com/health",
+	Status:       "down",
+	StatusCode:   503,
+	ResponseTime: 1234 * time.Millisecond,
+	Error:        "unexpected status code 503",
+	Subject:      "Service Alert: API is DOWN",
+	Message:      "The service 'API' is down.",
+	Time:         time.Date(2026, 3, 1, 12, 30, 45, 0, time.UTC),
+}
+
+// sendToServer sends the alert with the notifier to a test server and returns
+// the JSON body it received.
+func sendToServer(t *testing.T, notifier Notifier, alert Alert) interface{} {
+	t.Helper()
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	if err := notifier.Send(context.Background(), db.NotificationChannel{Target: server.URL}, alert); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	var got interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("invalid JSON body %q: %v", body, err)
+	}
+	return got
+}
+
+// assertJSON compares a decoded JSON body with the expected JSON document.
+func assertJSON(t *testing.T, got interface{}, expected string) {
+	t.Helper()
+	var want interface{}
+	if err := json.Unmarshal([]byte(expected), &want); err != nil {
+		t.Fatalf("invalid expected JSON: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.MarshalIndent(got, "", "  ")
+		t.Errorf("payload mismatch\ngot:\n%s\nwant:\n%s", gotJSON, expected)
+	}
+}
+
+func TestPostJSONFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := postJSON(context.Background(), server.Client(), server.URL, map[string]string{"text": "hi"})
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "invalid_token") {
+		t.Errorf("postJSON error = %v, want the status and body", err)
+	}
+}
@@ -1,12 +1,10 @@
 package web
 
 import (
-	"context"
 	"html/template"
 	"net/http"
 	"os"
 	"time"
-	"uptime-monitor/internal/database/db"
 	"uptime-monitor/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -35,7 +33,7 @@ func (s *Server) PostLoginPage(c *gin.Context) {
 	email := c.PostForm("email")
 	password := c.PostForm("password")
 
-	user, err := s.q.GetUserByEmail(context.Background(), email)
+	user, err := s.q.GetUserByEmail(c.Request.Context(), email)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			c.Redirect(http.StatusFound, "/login?error=invalid_credentials")
@@ -73,7 +71,7 @@ func (s *Server) PostLoginPage(c *gin.Context) {
 func (s *Server) ShowDashboardPage(c *gin.Context) {
 	userID := c.GetInt64("userID")
 
-	services, err := s.q.GetServicesForUser(context.Background(), userID)
+	services, err := s.q.GetServicesForUser(c.Request.Context(), userID)
 	if err != nil {
 		// Handle error - maybe render a dashboard with an error message
 		c.String(http.StatusInternalServerError, "Error fetching services: %v", err)
@@ -0,0 +1,39 @@
+package web
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LinkPage is the page opened by a link sent by email, such as an incident
+// acknowledgement link. Opening the link only shows a form posting back to
+// the same URL, so that mail scanners prefetching links don't act on them.
+type LinkPage struct {
+	Title   string
+	Message string
+	Button  string // Label of the form's submit button; no form if empty
+}
+
+// RenderLinkPage renders the page with the given status code.
+func RenderLinkPage(c *gin.Context, status int, page LinkPage) {
+	tmpl, err := template.ParseFiles("internal/web/templates/layout.html", "internal/web/templates/link_page.html")
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error rendering page: %v", err)
+		return
+	}
+
+	data := gin.H{
+		"title":   page.Title,
+		"Message": page.Message,
+		"Button":  page.Button,
+		"Action":  c.Request.URL.RequestURI(),
+	}
+
+	// The page acts on a secret in its URL, which must not leak to other sites
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("Cache-Control", "no-store")
+	c.Status(status)
+	tmpl.Execute(c.Writer, data)
+}
@@ -0,0 +1,13 @@
+package web
+
+import "uptime-monitor/internal/database/db"
+
+// Server holds the handlers of the server-rendered pages.
+type Server struct {
+	q *db.Queries
+}
+
+// NewServer creates the web handlers, querying the database with q.
+func NewServer(q *db.Queries) *Server {
+	return &Server{q: q}
+}
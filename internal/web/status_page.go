@@ -0,0 +1,337 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"uptime-monitor/internal/database/db"
+	"uptime-monitor/internal/monitoring"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// uptimeDays is the number of days in the uptime bars of status pages.
+const uptimeDays = 90
+
+// componentStatus is how the status of a component is shown to visitors.
+type componentStatus struct {
+	Label string
+	Class string // Badge colors
+	rank  int    // The worst component sets the page's overall status
+}
+
+var (
+	statusNoData      = componentStatus{"No data", "bg-slate-100 text-slate-600", 0}
+	statusOperational = componentStatus{"Operational", "bg-green-100 text-green-800", 0}
+	statusMaintenance = componentStatus{"Under maintenance", "bg-sky-100 text-sky-800", 1}
+	statusDegraded    = componentStatus{"Degraded performance", "bg-yellow-100 text-yellow-800", 2}
+	statusOutage      = componentStatus{"Major outage", "bg-red-100 text-red-800", 3}
+)
+
+// overallStatuses is the banner of a status page by the rank of its worst component.
+var overallStatuses = []componentStatus{
+	{"All systems operational", "bg-green-600 text-white", 0},
+	{"Scheduled maintenance in progress", "bg-sky-600 text-white", 1},
+	{"Some systems are experiencing degraded performance", "bg-yellow-500 text-white", 2},
+	{"Some systems are experiencing a major outage", "bg-red-600 text-white", 3},
+}
+
+type statusPageDay struct {
+	Title string // Tooltip with the date and uptime
+	Class string // Bar color
+}
+
+type statusPageComponent struct {
+	Name   string
+	Status componentStatus
+	Uptime string // Over the whole bar
+	Days   []statusPageDay
+}
+
+type statusPageGroup struct {
+	Name       string
+	Components []statusPageComponent
+}
+
+type statusPageIncident struct {
+	Title      string
+	Status     string
+	StartedAt  string
+	Components string
+	Update     string // Latest update posted by the page's owner, if any
+	UpdatedAt  string
+}
+
+type statusPageMaintenance struct {
+	Title       string
+	Description string
+	Components  string
+}
+
+// ShowStatusPage renders the public status page with the slug in the URL.
+func (s *Server) ShowStatusPage(c *gin.Context) {
+	page, err := s.q.GetStatusPageBySlug(c.Request.Context(), c.Param("slug"))
+	s.renderStatusPage(c, page, err)
+}
+
+// ShowStatusPageForDomain renders the public status page whose custom domain
+// is the host the request was made to.
+func (s *Server) ShowStatusPageForDomain(c *gin.Context) {
+	host := strings.ToLower(c.Request.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	page, err := s.q.GetStatusPageByDomain(c.Request.Context(), pgtype.Text{String: host, Valid: true})
+	s.renderStatusPage(c, page, err)
+}
+
+func (s *Server) renderStatusPage(c *gin.Context, page db.StatusPage, err error) {
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.String(http.StatusNotFound, "Status page not found")
+			return
+		}
+		log.Printf("ERROR: Could not get status page: %v", err)
+		c.String(http.StatusInternalServerError, "Error fetching status page")
+		return
+	}
+
+	data, err := s.statusPageData(c.Request.Context(), page)
+	if err != nil {
+		log.Printf("ERROR: Could not build status page %d: %v", page.ID, err)
+		c.String(http.StatusInternalServerError, "Error fetching status page")
+		return
+	}
+
+	tmpl, err := template.ParseFiles("internal/web/templates/layout.html", "internal/web/templates/status_page.html")
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error rendering page: %v", err)
+		return
+	}
+
+	// Visitors refresh often during an outage
+	c.Header("Cache-Control", "public, max-age=60")
+	c.Status(http.StatusOK)
+	tmpl.Execute(c.Writer, data)
+}
+
+// statusPageData gathers the current status, uptime bars, active incidents
+// and maintenance of the page's components.
+func (s *Server) statusPageData(ctx context.Context, page db.StatusPage) (gin.H, error) {
+	components, err := s.q.GetStatusPageComponents(ctx, page.ID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get components: %w", err)
+	}
+
+	var serviceIDs []int64
+	names := make(map[int64][]string) // Component names by service
+	for _, component := range components {
+		if _, ok := names[component.ServiceID]; !ok {
+			serviceIDs = append(serviceIDs, component.ServiceID)
+		}
+		names[component.ServiceID] = append(names[component.ServiceID], component.Name)
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	since := today.AddDate(0, 0, -(uptimeDays - 1))
+	daily, err := s.q.GetDailyUptimeForServices(ctx, db.GetDailyUptimeForServicesParams{
+		ServiceIds: serviceIDs,
+		Since:      pgtype.Timestamptz{Time: since, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get uptime: %w", err)
+	}
+	uptime := make(map[int64]map[string]db.GetDailyUptimeForServicesRow)
+	for _, row := range daily {
+		if uptime[row.ServiceID] == nil {
+			uptime[row.ServiceID] = make(map[string]db.GetDailyUptimeForServicesRow)
+		}
+		uptime[row.ServiceID][row.Day.Time.Format(time.DateOnly)] = row
+	}
+
+	// Maintenance windows in progress, each listed once
+	inMaintenance := make(map[int64]bool)
+	var maintenance []statusPageMaintenance
+	windowIndex := make(map[int64]int)
+	for _, serviceID := range serviceIDs {
+		windows, err := s.q.GetMaintenanceWindowsForService(ctx, db.GetMaintenanceWindowsForServiceParams{
+			ServiceID: serviceID,
+			At:        pgtype.Timestamptz{Time: now, Valid: true},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not get maintenance windows: %w", err)
+		}
+		for _, w := range windows {
+			if active, err := monitoring.MaintenanceActive(w, now); err != nil || !active {
+				continue
+			}
+			inMaintenance[serviceID] = true
+			i, ok := windowIndex[w.ID]
+			if !ok {
+				i = len(maintenance)
+				windowIndex[w.ID] = i
+				maintenance = append(maintenance, statusPageMaintenance{Title: w.Title, Description: w.Description.String})
+			}
+			maintenance[i].Components = joinNames(maintenance[i].Components, names[serviceID])
+		}
+	}
+
+	incidents, err := s.q.GetActiveIncidentsForServices(ctx, serviceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("could not get incidents: %w", err)
+	}
+	incidentIDs := make([]int64, 0, len(incidents))
+	for _, incident := range incidents {
+		incidentIDs = append(incidentIDs, incident.ID)
+	}
+	latest, err := s.q.GetLatestIncidentUpdates(ctx, incidentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("could not get incident updates: %w", err)
+	}
+	updates := make(map[int64]db.IncidentUpdate, len(latest))
+	for _, update := range latest {
+		updates[update.IncidentID] = update
+	}
+
+	activeIncidents := make([]statusPageIncident, 0, len(incidents))
+	for _, incident := range incidents {
+		status := monitoring.UpdateLabels[monitoring.UpdateInvestigating]
+		if incident.Status == monitoring.IncidentAcknowledged {
+			status = monitoring.UpdateLabels[monitoring.UpdateIdentified]
+		}
+		active := statusPageIncident{
+			Title:      incident.Title,
+			Status:     status,
+			StartedAt:  incident.StartedAt.Time.UTC().Format("Jan 2, 15:04 MST"),
+			Components: joinNames("", names[incident.ServiceID]),
+		}
+		if update, ok := updates[incident.ID]; ok {
+			active.Status = monitoring.UpdateLabels[update.Status]
+			active.Update = update.Message
+			active.UpdatedAt = update.CreatedAt.Time.UTC().Format("Jan 2, 15:04 MST")
+		}
+		activeIncidents = append(activeIncidents, active)
+	}
+
+	var groups []statusPageGroup
+	worst := 0
+	for _, component := range components {
+		if len(groups) == 0 || groups[len(groups)-1].Name != component.GroupName {
+			groups = append(groups, statusPageGroup{Name: component.GroupName})
+		}
+
+		status := serviceStatus(component.Status)
+		if inMaintenance[component.ServiceID] {
+			status = statusMaintenance
+		}
+		worst = max(worst, status.rank)
+
+		days, percent := uptimeBar(uptime[component.ServiceID], since)
+		group := &groups[len(groups)-1]
+		group.Components = append(group.Components, statusPageComponent{
+			Name:   component.Name,
+			Status: status,
+			Uptime: percent,
+			Days:   days,
+		})
+	}
+
+	return gin.H{
+		"title":       page.Title,
+		"Page":        page,
+		"Overall":     overallStatuses[worst],
+		"Groups":      groups,
+		"Incidents":   activeIncidents,
+		"Maintenance": maintenance,
+		"UptimeDays":  uptimeDays,
+		"UpdatedAt":   now.Format("Jan 2, 2006 15:04 MST"),
+	}, nil
+}
+
+// serviceStatus maps the confirmed status of a service to its component status.
+func serviceStatus(status pgtype.Text) componentStatus {
+	if !status.Valid {
+		return statusNoData
+	}
+	switch status.String {
+	case monitoring.StatusDegraded:
+		return statusDegraded
+	case monitoring.StatusDown, monitoring.StatusUnreachable:
+		return statusOutage
+	}
+	return statusOperational
+}
+
+// uptimeBar returns one bar per day since the given day and the uptime over
+// all of them. Only time up counts as uptime: degraded time is shown apart,
+// and unreachable time counts as down.
+func uptimeBar(daily map[string]db.GetDailyUptimeForServicesRow, since time.Time) ([]statusPageDay, string) {
+	days := make([]statusPageDay, 0, uptimeDays)
+	var monitored, up int64
+	for i := 0; i < uptimeDays; i++ {
+		date := since.AddDate(0, 0, i)
+		row, ok := daily[date.Format(time.DateOnly)]
+		total := row.UpSeconds + row.DegradedSeconds + row.DownSeconds
+		if !ok || total == 0 {
+			days = append(days, statusPageDay{Title: date.Format("Jan 2") + ": no data", Class: "bg-slate-200"})
+			continue
+		}
+		monitored += total
+		up += row.UpSeconds
+
+		percent := float64(row.UpSeconds) / float64(total) * 100
+		down := float64(row.DownSeconds) / float64(total) * 100
+		class := "bg-green-500"
+		switch {
+		case down >= 5:
+			class = "bg-red-500"
+		case down >= 1:
+			class = "bg-orange-400"
+		case percent < 99.9:
+			class = "bg-yellow-400"
+		}
+
+		title := fmt.Sprintf("%s: %.2f%% uptime", date.Format("Jan 2"), percent)
+		if row.DegradedSeconds > 0 {
+			title += ", " + formatDuration(row.DegradedSeconds) + " degraded"
+		}
+		if row.DownSeconds > 0 {
+			title += ", " + formatDuration(row.DownSeconds) + " down"
+		}
+		days = append(days, statusPageDay{Title: title, Class: class})
+	}
+
+	if monitored == 0 {
+		return days, "No data"
+	}
+	return days, fmt.Sprintf("%.2f%% uptime", float64(up)/float64(monitored)*100)
+}
+
+// formatDuration formats seconds as hours and minutes, rounded up to a minute.
+func formatDuration(seconds int64) string {
+	minutes := (seconds + 59) / 60
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
+
+// joinNames appends the component names to a comma-separated list.
+func joinNames(list string, names []string) string {
+	for _, name := range names {
+		if list != "" {
+			list += ", "
+		}
+		list += name
+	}
+	return list
+}